@@ -44,6 +44,20 @@ func TestGetSupportedEC2Platforms(t *testing.T) {
 // getMockedAwsApiSession establishes a httptest server to simulate behaviour
 // of a real AWS API server
 func getMockedAwsApiSession(svcName string, endpoints []*awsMockEndpoint) (func(), *session.Session, error) {
+	return getMockedAwsApiSessionWithResponder(svcName, func(r *http.Request, requestBody string) *awsMockResponse {
+		for _, e := range endpoints {
+			if r.Method == e.Request.Method && r.RequestURI == e.Request.Uri && requestBody == e.Request.Body {
+				return e.Response
+			}
+		}
+		return nil
+	})
+}
+
+// getMockedAwsApiSessionWithResponder establishes a httptest server like
+// getMockedAwsApiSession, answering each request with the response chosen
+// by respond, or with a 400 if it returns nil
+func getMockedAwsApiSessionWithResponder(svcName string, respond func(r *http.Request, requestBody string) *awsMockResponse) (func(), *session.Session, error) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(r.Body)
@@ -52,23 +66,21 @@ func getMockedAwsApiSession(svcName string, endpoints []*awsMockEndpoint) (func(
 		log.Printf("[DEBUG] Received %s API %q request to %q: %s",
 			svcName, r.Method, r.RequestURI, requestBody)
 
-		for _, e := range endpoints {
-			if r.Method == e.Request.Method && r.RequestURI == e.Request.Uri && requestBody == e.Request.Body {
-				log.Printf("[DEBUG] Mocked %s API responding with %d: %s",
-					svcName, e.Response.StatusCode, e.Response.Body)
+		resp := respond(r, requestBody)
+		if resp == nil {
+			w.WriteHeader(400)
+			return
+		}
 
-				w.WriteHeader(e.Response.StatusCode)
-				w.Header().Set("Content-Type", e.Response.ContentType)
-				w.Header().Set("X-Amzn-Requestid", "1b206dd1-f9a8-11e5-becf-051c60f11c4a")
-				w.Header().Set("Date", time.Now().Format(time.RFC1123))
+		log.Printf("[DEBUG] Mocked %s API responding with %d: %s",
+			svcName, resp.StatusCode, resp.Body)
 
-				fmt.Fprintln(w, e.Response.Body)
-				return
-			}
-		}
+		w.Header().Set("Content-Type", resp.ContentType)
+		w.Header().Set("X-Amzn-Requestid", "1b206dd1-f9a8-11e5-becf-051c60f11c4a")
+		w.Header().Set("Date", time.Now().Format(time.RFC1123))
+		w.WriteHeader(resp.StatusCode)
 
-		w.WriteHeader(400)
-		return
+		fmt.Fprintln(w, resp.Body)
 	}))
 
 	sc := awsCredentials.NewStaticCredentials("accessKey", "secretKey", "")
@@ -0,0 +1,188 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceAwsCloudFormationStackSet() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsCloudFormationStackSetRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"arn"},
+			},
+			"arn": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"name"},
+				ValidateFunc:  validateArn,
+			},
+			"stack_set_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"template_body": {
+				Type:     schema.TypeString,
+				Computed: true,
+				StateFunc: func(v interface{}) string {
+					template, _ := normalizeCloudFormationTemplate(v)
+					return template
+				},
+			},
+			"detected_template_format": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"transforms": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"capabilities": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"parameters": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"last_operation_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"last_operation_status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"tags": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceAwsCloudFormationStackSetRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	// The ID of an ARN names a stack set even after it was deleted and its
+	// name reused, so it is described by ID rather than by name
+	name := d.Get("name").(string)
+	if v, ok := d.GetOk("arn"); ok {
+		var err error
+		name, err = cloudFormationStackSetIdFromArn(meta.(*AWSClient), v.(string))
+		if err != nil {
+			return err
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("One of name or arn must be set")
+	}
+
+	input := &cloudformation.DescribeStackSetInput{
+		StackSetName: aws.String(name),
+	}
+
+	log.Printf("[DEBUG] Reading CloudFormation stack set: %s", input)
+	out, err := conn.DescribeStackSet(input)
+	if err != nil {
+		return fmt.Errorf("Failed describing CloudFormation stack set (%s): %s", name, err)
+	}
+	stackSet := out.StackSet
+	d.SetId(*stackSet.StackSetId)
+
+	d.Set("name", stackSet.StackSetName)
+	// Keep the ARN the stack set was looked up by, the provider may not know
+	// its account id (skip_requesting_account_id)
+	if _, ok := d.GetOk("arn"); !ok {
+		d.Set("arn", cloudFormationStackSetArn(meta.(*AWSClient), *stackSet.StackSetId))
+	}
+	d.Set("stack_set_id", stackSet.StackSetId)
+	d.Set("description", stackSet.Description)
+	d.Set("status", stackSet.Status)
+	d.Set("parameters", flattenAllCloudFormationParameters(stackSet.Parameters))
+	d.Set("tags", flattenCloudFormationTags(stackSet.Tags))
+
+	if len(stackSet.Capabilities) > 0 {
+		d.Set("capabilities", schema.NewSet(schema.HashString, flattenStringList(stackSet.Capabilities)))
+	}
+
+	lastOperation, err := findCloudFormationStackSetLastOperation(conn, *stackSet.StackSetId)
+	if err != nil {
+		return err
+	}
+	if lastOperation != nil {
+		d.Set("last_operation_id", lastOperation.OperationId)
+		d.Set("last_operation_status", lastOperation.Status)
+	}
+
+	// DescribeStackSet returns the template body directly,
+	// GetTemplate only works for stacks and not for stack sets
+	if stackSet.TemplateBody != nil {
+		template, err := normalizeCloudFormationTemplate(*stackSet.TemplateBody)
+		if err != nil {
+			return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+		}
+		d.Set("template_body", template)
+
+		d.Set("detected_template_format", cloudFormationTemplateFormat(template))
+
+		transforms, err := flattenCloudFormationTemplateTransforms(template)
+		if err != nil {
+			return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+		}
+		if err := d.Set("transforms", transforms); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cloudFormationStackSetIdFromArn returns the ID of the stack set identified
+// by an ARN of the form arn:aws:cloudformation:<region>:<account>:stackset/<name>:<uuid>.
+// Stack sets are only read from the region and account of the provider, so
+// the ARN must belong to them. The account is only checked when known.
+func cloudFormationStackSetIdFromArn(client *AWSClient, stackSetArn string) (string, error) {
+	parsed, err := arn.Parse(stackSetArn)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing CloudFormation stack set ARN (%s): %s", stackSetArn, err)
+	}
+
+	stackSetId := strings.TrimPrefix(parsed.Resource, "stackset/")
+	if parsed.Service != cloudformation.ServiceName || stackSetId == parsed.Resource || stackSetId == "" {
+		return "", fmt.Errorf("%q is not a CloudFormation stack set ARN", stackSetArn)
+	}
+
+	if parsed.Region != client.region {
+		return "", fmt.Errorf("CloudFormation stack set ARN %q is not in the region of the provider (%s)", stackSetArn, client.region)
+	}
+	if client.accountid != "" && parsed.AccountID != client.accountid {
+		return "", fmt.Errorf("CloudFormation stack set ARN %q is not in the account of the provider (%s)", stackSetArn, client.accountid)
+	}
+
+	return stackSetId, nil
+}
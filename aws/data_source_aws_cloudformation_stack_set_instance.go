@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceAwsCloudFormationStackSetInstance() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsCloudFormationStackSetInstanceRead,
+
+		Schema: map[string]*schema.Schema{
+			"stack_set_name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"account_id": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAwsAccountId,
+			},
+			"region": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"stack_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"status": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"status_reason": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"parameter_overrides": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceAwsCloudFormationStackSetInstanceRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName := d.Get("stack_set_name").(string)
+	accountId := d.Get("account_id").(string)
+	region := d.Get("region").(string)
+
+	input := &cloudformation.DescribeStackInstanceInput{
+		StackSetName:         aws.String(stackSetName),
+		StackInstanceAccount: aws.String(accountId),
+		StackInstanceRegion:  aws.String(region),
+	}
+
+	log.Printf("[DEBUG] Reading CloudFormation stack set instance: %s", input)
+	out, err := conn.DescribeStackInstance(input)
+	if err != nil {
+		return fmt.Errorf("Failed describing CloudFormation stack set %q instance of account %s (%s): %s", stackSetName, accountId, region, err)
+	}
+	stackInstance := out.StackInstance
+	d.SetId(strings.Join([]string{stackSetName, accountId, region}, ","))
+
+	d.Set("stack_id", stackInstance.StackId)
+	d.Set("status", stackInstance.Status)
+	d.Set("status_reason", stackInstance.StatusReason)
+	if err := d.Set("parameter_overrides", flattenAllCloudFormationParameters(stackInstance.ParameterOverrides)); err != nil {
+		return err
+	}
+
+	return nil
+}
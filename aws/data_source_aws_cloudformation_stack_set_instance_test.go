@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestAccAWSCloudFormationStackSetInstance_dataSource_basic(t *testing.T) {
+	stackSetName := fmt.Sprintf("tf-acc-ds-instance-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckAwsCloudFormationStackSetInstanceDataSourceConfig_basic(stackSetName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.aws_cloudformation_stack_set_instance.test", "stack_id", "aws_cloudformation_stack_set_instance.test", "stack_id"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set_instance.test", "status", "CURRENT"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set_instance.test", "parameter_overrides.%", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceAwsCloudFormationStackSetInstanceRead(t *testing.T) {
+	response := strings.Replace(testCloudFormationDescribeStackInstanceResponse(cloudformation.StackInstanceStatusInoperable), "</Status>", `</Status>
+      <StatusReason>Account 123456789012 should have 'AWSCloudFormationStackSetExecutionRole' role</StatusReason>
+      <ParameterOverrides>
+        <member>
+          <ParameterKey>VpcCidr</ParameterKey>
+          <ParameterValue>10.1.0.0/16</ParameterValue>
+        </member>
+      </ParameterOverrides>`, 1)
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackInstance": {
+			{200, response, "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSetInstance().Schema, map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"account_id":     "123456789012",
+		"region":         "us-east-1",
+	})
+
+	err = dataSourceAwsCloudFormationStackSetInstanceRead(d, &AWSClient{cfconn: conn})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	input := requests["DescribeStackInstance"][0]
+	if input.Get("StackSetName") != "tf-test" || input.Get("StackInstanceAccount") != "123456789012" || input.Get("StackInstanceRegion") != "us-east-1" {
+		t.Fatalf("Expected the stack instance of 123456789012 in us-east-1 to be described, received: %v", input)
+	}
+
+	expected := map[string]interface{}{
+		"stack_id":                    "arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-0123/2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		"status":                      "INOPERABLE",
+		"status_reason":               "Account 123456789012 should have 'AWSCloudFormationStackSetExecutionRole' role",
+		"parameter_overrides.VpcCidr": "10.1.0.0/16",
+	}
+	for k, v := range expected {
+		if actual := d.Get(k); actual != v {
+			t.Fatalf("Expected %s to be %q, received: %q", k, v, actual)
+		}
+	}
+	if d.Id() != "tf-test,123456789012,us-east-1" {
+		t.Fatalf("Expected the ID of the stack set instance resource, received: %q", d.Id())
+	}
+}
+
+func testAccCheckAwsCloudFormationStackSetInstanceDataSourceConfig_basic(stackSetName string) string {
+	return testAccAWSCloudFormationStackSetInstanceConfig(stackSetName) + `
+data "aws_cloudformation_stack_set_instance" "test" {
+  stack_set_name = "${aws_cloudformation_stack_set_instance.test.stack_set_name}"
+  account_id     = "${aws_cloudformation_stack_set_instance.test.account_id}"
+  region         = "${aws_cloudformation_stack_set_instance.test.region}"
+}
+`
+}
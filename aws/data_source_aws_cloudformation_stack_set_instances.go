@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceAwsCloudFormationStackSetInstances() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsCloudFormationStackSetInstancesRead,
+
+		Schema: map[string]*schema.Schema{
+			"stack_set_name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"account_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateAwsAccountId,
+			},
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"stack_instances": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"account_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"region": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"stack_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceAwsCloudFormationStackSetInstancesRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	input := &cloudformation.ListStackInstancesInput{
+		StackSetName: aws.String(d.Get("stack_set_name").(string)),
+	}
+	if v, ok := d.GetOk("account_id"); ok {
+		input.StackInstanceAccount = aws.String(v.(string))
+	}
+	if v, ok := d.GetOk("region"); ok {
+		input.StackInstanceRegion = aws.String(v.(string))
+	}
+
+	// Paging advances the token of the input
+	id := fmt.Sprintf("%d", hashcode.String(input.String()))
+
+	log.Printf("[DEBUG] Reading CloudFormation stack set instances: %s", input)
+	instances, err := filterCloudFormationStackSetInstances(conn, input)
+	if err != nil {
+		return fmt.Errorf("Failed listing CloudFormation stack set %q instances: %s", *input.StackSetName, err)
+	}
+
+	d.SetId(id)
+
+	if err := d.Set("stack_instances", flattenCloudFormationStackInstances(instances, nil, nil)); err != nil {
+		return err
+	}
+
+	return nil
+}
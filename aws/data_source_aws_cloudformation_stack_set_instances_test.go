@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestAccAWSCloudFormationStackSetInstances_dataSource_basic(t *testing.T) {
+	stackSetName := fmt.Sprintf("tf-acc-ds-instances-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckAwsCloudFormationStackSetInstancesDataSourceConfig_basic(stackSetName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set_instances.test", "stack_instances.#", "1"),
+					resource.TestCheckResourceAttrPair("data.aws_cloudformation_stack_set_instances.test", "stack_instances.0.stack_id", "aws_cloudformation_stack_set_instance.test", "stack_id"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set_instances.test", "stack_instances.0.status", "CURRENT"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceAwsCloudFormationStackSetInstancesRead(t *testing.T) {
+	cases := []struct {
+		Config   map[string]interface{}
+		Expected map[string]string
+	}{
+		{
+			Config:   map[string]interface{}{"stack_set_name": "tf-test"},
+			Expected: map[string]string{"StackSetName": "tf-test"},
+		},
+		{
+			Config: map[string]interface{}{
+				"stack_set_name": "tf-test",
+				"account_id":     "123456789012",
+				"region":         "eu-west-1",
+			},
+			Expected: map[string]string{
+				"StackSetName":         "tf-test",
+				"StackInstanceAccount": "123456789012",
+				"StackInstanceRegion":  "eu-west-1",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"ListStackInstances": {
+				{200, testCloudFormationListStackInstancesStatusResponse(
+					"210987654321", "us-east-1", "CURRENT",
+					"123456789012", "us-east-1", "OUTDATED",
+					"123456789012", "eu-west-1", "CURRENT",
+				), "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSetInstances().Schema, tc.Config)
+		err = dataSourceAwsCloudFormationStackSetInstancesRead(d, &AWSClient{cfconn: conn})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		input := requests["ListStackInstances"][0]
+		for _, k := range []string{"StackInstanceAccount", "StackInstanceRegion"} {
+			if _, ok := tc.Expected[k]; !ok && input.Get(k) != "" {
+				t.Fatalf("%d: Expected no %s filter, received: %v", i, k, input)
+			}
+		}
+		for k, v := range tc.Expected {
+			if actual := input.Get(k); actual != v {
+				t.Fatalf("%d: Expected %s to be %q, received: %q", i, k, v, actual)
+			}
+		}
+
+		// The mocked response isn't filtered, all instances are sorted
+		expected := []string{"123456789012 eu-west-1 CURRENT", "123456789012 us-east-1 OUTDATED", "210987654321 us-east-1 CURRENT"}
+		if n := d.Get("stack_instances.#").(int); n != len(expected) {
+			t.Fatalf("%d: Expected %d stack instances, received %d", i, len(expected), n)
+		}
+		for j, v := range expected {
+			prefix := fmt.Sprintf("stack_instances.%d.", j)
+			actual := fmt.Sprintf("%s %s %s", d.Get(prefix+"account_id"), d.Get(prefix+"region"), d.Get(prefix+"status"))
+			if actual != v {
+				t.Fatalf("%d: Expected stack instance %d to be %q, received: %q", i, j, v, actual)
+			}
+		}
+	}
+}
+
+func TestDataSourceAwsCloudFormationStackSetInstancesRead_pagedId(t *testing.T) {
+	stackIds := []string{
+		"arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test/1",
+		"arn:aws:cloudformation:eu-west-1:123456789012:stack/StackSet-tf-test/2",
+	}
+	pages := [][]*awsMockResponse{
+		{
+			{200, testCloudFormationListStackInstancesResponse("", stackIds...), "text/xml"},
+		},
+		{
+			{200, testCloudFormationListStackInstancesResponse("page-2", stackIds[0]), "text/xml"},
+			{200, testCloudFormationListStackInstancesResponse("", stackIds[1]), "text/xml"},
+		},
+	}
+
+	// The ID only depends on the configured filters, not on the pages read
+	var ids []string
+	for i, responses := range pages {
+		closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"ListStackInstances": responses,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSetInstances().Schema, map[string]interface{}{
+			"stack_set_name": "tf-test",
+		})
+		err = dataSourceAwsCloudFormationStackSetInstancesRead(d, &AWSClient{cfconn: conn})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+		if n := d.Get("stack_instances.#").(int); n != len(stackIds) {
+			t.Fatalf("%d: Expected %d stack instances, received %d", i, len(stackIds), n)
+		}
+		ids = append(ids, d.Id())
+	}
+
+	if ids[0] != ids[1] {
+		t.Fatalf("Expected the same ID whether paging or not, received %q and %q", ids[0], ids[1])
+	}
+}
+
+func testAccCheckAwsCloudFormationStackSetInstancesDataSourceConfig_basic(stackSetName string) string {
+	return testAccAWSCloudFormationStackSetInstanceConfig(stackSetName) + `
+data "aws_cloudformation_stack_set_instances" "test" {
+  stack_set_name = "${aws_cloudformation_stack_set_instance.test.stack_set_name}"
+  region         = "${aws_cloudformation_stack_set_instance.test.region}"
+}
+`
+}
@@ -0,0 +1,272 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestAccAWSCloudFormationStackSet_dataSource_basic(t *testing.T) {
+	stackSetName := fmt.Sprintf("tf-acc-ds-stack-set-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckAwsCloudFormationStackSetDataSourceConfig_basic(stackSetName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.aws_cloudformation_stack_set.network", "stack_set_id", "aws_cloudformation_stack_set.cfs", "stack_set_id"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "description", "Terraform acceptance test"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "status", "ACTIVE"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "last_operation_id", ""),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "capabilities.#", "0"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "parameters.%", "1"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "parameters.CIDR", "10.10.10.0/24"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "tags.%", "1"),
+					resource.TestCheckResourceAttr("data.aws_cloudformation_stack_set.network", "tags.Name", "Form the Cloud"),
+					resource.TestMatchResourceAttr("data.aws_cloudformation_stack_set.network", "template_body",
+						regexp.MustCompile("AWS::EC2::VPC")),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceAwsCloudFormationStackSetRead_lastOperation(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsPageResponse("page-2",
+				"terraform-20171012000000000000000001", "SUCCEEDED", "2017-10-12T10:00:00Z",
+				"terraform-20171012000000000000000003", "FAILED", "2017-10-12T12:00:00Z",
+			), "text/xml"},
+			{200, testCloudFormationListStackSetOperationsPageResponse("",
+				"terraform-20171012000000000000000002", "SUCCEEDED", "2017-10-12T11:00:00Z",
+			), "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name": "tf-test",
+	})
+
+	err = dataSourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if v := d.Get("last_operation_id").(string); v != "terraform-20171012000000000000000003" {
+		t.Fatalf("Expected the latest operation ID, received: %q", v)
+	}
+	if v := d.Get("last_operation_status").(string); v != "FAILED" {
+		t.Fatalf("Expected the latest operation status, received: %q", v)
+	}
+	if n := len(requests["ListStackSetOperations"]); n != 2 {
+		t.Fatalf("Expected both pages of operations to be listed, received %d requests", n)
+	}
+}
+
+func TestDataSourceAwsCloudFormationStackSetRead_nameOrArn(t *testing.T) {
+	cases := []struct {
+		Config   map[string]interface{}
+		Expected string
+	}{
+		{
+			Config:   map[string]interface{}{"name": "tf-test"},
+			Expected: "tf-test",
+		},
+		{
+			Config:   map[string]interface{}{"arn": "arn:aws:cloudformation:us-east-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346"},
+			Expected: "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"DescribeStackSet": {
+				{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+			},
+			"ListStackSetOperations": {
+				{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSet().Schema, tc.Config)
+		err = dataSourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		if v := requests["DescribeStackSet"][0].Get("StackSetName"); v != tc.Expected {
+			t.Fatalf("%d: Expected the stack set to be described by %q, received %q", i, tc.Expected, v)
+		}
+		// The operations of the described stack set, not of one reusing its name
+		if v := requests["ListStackSetOperations"][0].Get("StackSetName"); v != "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346" {
+			t.Fatalf("%d: Expected the operations to be listed by stack set ID, received %q", i, v)
+		}
+		if v := d.Get("name").(string); v != "tf-test" {
+			t.Fatalf("%d: Expected name to be set, received %q", i, v)
+		}
+		if v := d.Get("arn").(string); v != "arn:aws:cloudformation:us-east-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346" {
+			t.Fatalf("%d: Expected arn to be set, received %q", i, v)
+		}
+	}
+
+	// The given ARN is kept when the provider doesn't know its account
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	givenArn := "arn:aws:cloudformation:us-east-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346"
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"arn": givenArn,
+	})
+	err = dataSourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1"})
+	closeFunc()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if v := d.Get("arn").(string); v != givenArn {
+		t.Fatalf("Expected the given arn to be kept, received %q", v)
+	}
+
+	d = schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSet().Schema, map[string]interface{}{})
+	if err := dataSourceAwsCloudFormationStackSetRead(d, &AWSClient{}); err == nil {
+		t.Fatal("Expected an error without name and arn")
+	}
+}
+
+func TestDataSourceAwsCloudFormationStackSetRead_region(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	// The client of a provider alias configured for another region
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name": "tf-test",
+	})
+	err = dataSourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "eu-west-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if v := d.Get("arn").(string); v != "arn:aws:cloudformation:eu-west-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346" {
+		t.Fatalf("Expected the ARN to be in the region of the provider, received %q", v)
+	}
+	if v := d.Get("template_body").(string); v == "" {
+		t.Fatal("Expected the template body to be read")
+	}
+	for action := range requests {
+		if action != "DescribeStackSet" && action != "ListStackSetOperations" {
+			t.Fatalf("Expected the template to be read by DescribeStackSet only, received a %s request", action)
+		}
+	}
+}
+
+func TestCloudFormationStackSetIdFromArn(t *testing.T) {
+	client := &AWSClient{partition: "aws", region: "us-east-1", accountid: "123456789012"}
+
+	cases := []struct {
+		Arn         string
+		Client      *AWSClient
+		Expected    string
+		ExpectError bool
+	}{
+		{Arn: "arn:aws:cloudformation:us-east-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", Expected: "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346"},
+		{Arn: "arn:aws-us-gov:cloudformation:us-gov-west-1:123456789012:stackset/tf-test", Client: &AWSClient{partition: "aws-us-gov", region: "us-gov-west-1", accountid: "123456789012"}, Expected: "tf-test"},
+		// The account isn't checked when the provider doesn't know it
+		{Arn: "arn:aws:cloudformation:us-east-1:210987654321:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", Client: &AWSClient{partition: "aws", region: "us-east-1"}, Expected: "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346"},
+		{Arn: "arn:aws:cloudformation:eu-west-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", ExpectError: true},
+		{Arn: "arn:aws:cloudformation:us-east-1:210987654321:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", ExpectError: true},
+		{Arn: "arn:aws:cloudformation:us-east-1:123456789012:stack/tf-test/2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", ExpectError: true},
+		{Arn: "arn:aws:sns:us-east-1:123456789012:stackset/tf-test", ExpectError: true},
+		{Arn: "tf-test", ExpectError: true},
+	}
+
+	for _, tc := range cases {
+		c := tc.Client
+		if c == nil {
+			c = client
+		}
+		id, err := cloudFormationStackSetIdFromArn(c, tc.Arn)
+		if tc.ExpectError {
+			if err == nil {
+				t.Fatalf("Expected an error for %q", tc.Arn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", tc.Arn, err)
+		}
+		if id != tc.Expected {
+			t.Fatalf("Expected ID %q for %q, received %q", tc.Expected, tc.Arn, id)
+		}
+	}
+}
+
+func testAccCheckAwsCloudFormationStackSetDataSourceConfig_basic(stackSetName string) string {
+	return fmt.Sprintf(`
+resource "aws_cloudformation_stack_set" "cfs" {
+  name        = "%s"
+  description = "Terraform acceptance test"
+  parameters {
+    CIDR = "10.10.10.0/24"
+  }
+  template_body = <<STACK
+{
+  "Parameters": {
+    "CIDR": {
+      "Type": "String"
+    }
+  },
+  "Resources" : {
+    "myvpc": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : { "Ref" : "CIDR" }
+      }
+    }
+  }
+}
+STACK
+  tags {
+    Name = "Form the Cloud"
+  }
+}
+
+data "aws_cloudformation_stack_set" "network" {
+  name = "${aws_cloudformation_stack_set.cfs.name}"
+}
+`, stackSetName)
+}
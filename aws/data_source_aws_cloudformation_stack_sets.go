@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func dataSourceAwsCloudFormationStackSets() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsCloudFormationStackSetsRead,
+
+		Schema: map[string]*schema.Schema{
+			"status": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					cloudformation.StackSetStatusActive,
+					cloudformation.StackSetStatusDeleted,
+				}, false),
+			},
+			"names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"stack_sets": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"stack_set_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceAwsCloudFormationStackSetsRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	input := &cloudformation.ListStackSetsInput{}
+	if v, ok := d.GetOk("status"); ok {
+		input.Status = aws.String(v.(string))
+	}
+
+	// Paging advances the token of the input
+	id := fmt.Sprintf("%d", hashcode.String(input.String()))
+
+	// The summaries have all exported attributes, so no stack set has to be
+	// described on its own, however many there are
+	log.Printf("[DEBUG] Reading CloudFormation stack sets: %s", input)
+	summaries, err := listCloudFormationStackSets(conn, input)
+	if err != nil {
+		return fmt.Errorf("Failed listing CloudFormation stack sets: %s", err)
+	}
+
+	d.SetId(id)
+
+	stackSets := flattenCloudFormationStackSetSummaries(summaries)
+	names := make([]string, 0, len(stackSets))
+	ids := make([]string, 0, len(stackSets))
+	for _, stackSet := range stackSets {
+		names = append(names, stackSet["name"].(string))
+		ids = append(ids, stackSet["stack_set_id"].(string))
+	}
+
+	if err := d.Set("names", names); err != nil {
+		return err
+	}
+	if err := d.Set("ids", ids); err != nil {
+		return err
+	}
+	if err := d.Set("stack_sets", stackSets); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// listCloudFormationStackSets pages through the stack sets matching the input
+func listCloudFormationStackSets(conn *cloudformation.CloudFormation, input *cloudformation.ListStackSetsInput) ([]*cloudformation.StackSetSummary, error) {
+	var summaries []*cloudformation.StackSetSummary
+	for {
+		resp, err := conn.ListStackSets(input)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, resp.Summaries...)
+
+		if resp.NextToken == nil {
+			return summaries, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// flattenCloudFormationStackSetSummaries returns the stack sets sorted by
+// name. A deleted stack set keeps its name, so the ID breaks ties with the
+// stack sets reusing it.
+func flattenCloudFormationStackSetSummaries(summaries []*cloudformation.StackSetSummary) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, map[string]interface{}{
+			"name":         aws.StringValue(summary.StackSetName),
+			"stack_set_id": aws.StringValue(summary.StackSetId),
+			"description":  aws.StringValue(summary.Description),
+			"status":       aws.StringValue(summary.Status),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i]["name"] != result[j]["name"] {
+			return result[i]["name"].(string) < result[j]["name"].(string)
+		}
+		return result[i]["stack_set_id"].(string) < result[j]["stack_set_id"].(string)
+	})
+
+	return result
+}
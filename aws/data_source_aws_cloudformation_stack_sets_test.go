@@ -0,0 +1,107 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestDataSourceAwsCloudFormationStackSetsRead_pages(t *testing.T) {
+	// Three pages of 100 stack sets each, listed in reverse order
+	var pages []*awsMockResponse
+	for page := 0; page < 3; page++ {
+		var stackSets []string
+		for i := 100 * (3 - page); i > 100*(2-page); i-- {
+			name := fmt.Sprintf("tf-test-%03d", i)
+			stackSets = append(stackSets, name, name+":2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", "ACTIVE")
+		}
+		var nextToken string
+		if page < 2 {
+			nextToken = fmt.Sprintf("page-%d", page+2)
+		}
+		pages = append(pages, &awsMockResponse{200, testCloudFormationListStackSetsResponse(nextToken, stackSets...), "text/xml"})
+	}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackSets": pages,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSets().Schema, map[string]interface{}{
+		"status": "ACTIVE",
+	})
+	if err := dataSourceAwsCloudFormationStackSetsRead(d, &AWSClient{cfconn: conn}); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	for action := range requests {
+		if action != "ListStackSets" {
+			t.Fatalf("Expected the stack sets to be read by ListStackSets only, received a %s request", action)
+		}
+	}
+	lists := requests["ListStackSets"]
+	if len(lists) != 3 {
+		t.Fatalf("Expected a ListStackSets request per page, received %d", len(lists))
+	}
+	for i, input := range lists {
+		if v := input.Get("Status"); v != "ACTIVE" {
+			t.Fatalf("%d: Expected the status filter ACTIVE, received %q", i, v)
+		}
+		var expected string
+		if i > 0 {
+			expected = fmt.Sprintf("page-%d", i+1)
+		}
+		if v := input.Get("NextToken"); v != expected {
+			t.Fatalf("%d: Expected NextToken %q, received %q", i, expected, v)
+		}
+	}
+
+	names := d.Get("names").([]interface{})
+	if len(names) != 300 {
+		t.Fatalf("Expected 300 stack sets, received %d", len(names))
+	}
+	if names[0] != "tf-test-001" || names[299] != "tf-test-300" {
+		t.Fatalf("Expected the stack sets to be sorted by name, received %q to %q", names[0], names[299])
+	}
+	if v := d.Get("ids.0").(string); v != "tf-test-001:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346" {
+		t.Fatalf("Expected the IDs in the order of the names, received %q", v)
+	}
+	if v := d.Get("stack_sets.299.status").(string); v != "ACTIVE" {
+		t.Fatalf("Expected the status of the stack sets, received %q", v)
+	}
+}
+
+func TestDataSourceAwsCloudFormationStackSetsRead_allStatuses(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackSets": {
+			{200, testCloudFormationListStackSetsResponse("",
+				"tf-test", "tf-test:6a5b4b2c-1fa9-4b44-8bf5-2bf71b0b7346", "ACTIVE",
+				"tf-test", "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", "DELETED",
+			), "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, dataSourceAwsCloudFormationStackSets().Schema, map[string]interface{}{})
+	if err := dataSourceAwsCloudFormationStackSetsRead(d, &AWSClient{cfconn: conn}); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if v := requests["ListStackSets"][0].Get("Status"); v != "" {
+		t.Fatalf("Expected no status filter, received %q", v)
+	}
+	// A deleted stack set and the one reusing its name are ordered by ID
+	if v := d.Get("stack_sets.0.status").(string); v != "DELETED" {
+		t.Fatalf("Expected the deleted stack set first, received %q", v)
+	}
+	if v := d.Get("stack_sets.1.stack_set_id").(string); v != "tf-test:6a5b4b2c-1fa9-4b44-8bf5-2bf71b0b7346" {
+		t.Fatalf("Expected the active stack set second, received %q", v)
+	}
+}
@@ -76,6 +76,26 @@ func suppressOpenIdURL(k, old, new string, d *schema.ResourceData) bool {
 	return oldUrl.String() == newUrl.String()
 }
 
+// suppressUrlQueryDiffs ignores URLs only differing in their query string,
+// e.g. a regenerated presigned S3 URL of the same object
+func suppressUrlQueryDiffs(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return false
+	}
+
+	oldUrl, err := url.Parse(old)
+	if err != nil {
+		return false
+	}
+
+	newUrl, err := url.Parse(new)
+	if err != nil {
+		return false
+	}
+
+	return oldUrl.Scheme == newUrl.Scheme && oldUrl.Host == newUrl.Host && oldUrl.Path == newUrl.Path
+}
+
 func suppressAutoscalingGroupAvailabilityZoneDiffs(k, old, new string, d *schema.ResourceData) bool {
 	// If VPC zone identifiers are provided then there is no need to explicitly
 	// specify availability zones.
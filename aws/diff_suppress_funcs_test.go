@@ -29,3 +29,45 @@ func TestSuppressEquivalentJsonDiffsWhitespaceAndNoWhitespace(t *testing.T) {
 		t.Errorf("Expected suppressEquivalentJsonDiffs to return false for %s == %s", noWhitespaceDiff, whitespaceDiff)
 	}
 }
+
+func TestSuppressUrlQueryDiffs(t *testing.T) {
+	d := new(schema.ResourceData)
+
+	cases := []struct {
+		Old      string
+		New      string
+		Suppress bool
+	}{
+		{
+			Old:      "https://bucket.s3.amazonaws.com/template.json?X-Amz-Expires=900&X-Amz-Signature=abc",
+			New:      "https://bucket.s3.amazonaws.com/template.json?X-Amz-Expires=900&X-Amz-Signature=def",
+			Suppress: true,
+		},
+		{
+			Old:      "https://bucket.s3.amazonaws.com/template.json",
+			New:      "https://bucket.s3.amazonaws.com/template.json?X-Amz-Signature=def",
+			Suppress: true,
+		},
+		{
+			Old:      "https://bucket.s3.amazonaws.com/template.json?X-Amz-Signature=abc",
+			New:      "https://bucket.s3.amazonaws.com/other.json?X-Amz-Signature=abc",
+			Suppress: false,
+		},
+		{
+			Old:      "https://bucket.s3.amazonaws.com/template.json",
+			New:      "https://other.s3.amazonaws.com/template.json",
+			Suppress: false,
+		},
+		{
+			Old:      "",
+			New:      "https://bucket.s3.amazonaws.com/template.json",
+			Suppress: false,
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := suppressUrlQueryDiffs("template_url", tc.Old, tc.New, d); actual != tc.Suppress {
+			t.Errorf("Expected suppressUrlQueryDiffs to return %t for %q and %q", tc.Suppress, tc.Old, tc.New)
+		}
+	}
+}
@@ -160,72 +160,76 @@ func Provider() terraform.ResourceProvider {
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"aws_acm_certificate":            dataSourceAwsAcmCertificate(),
-			"aws_ami":                        dataSourceAwsAmi(),
-			"aws_ami_ids":                    dataSourceAwsAmiIds(),
-			"aws_autoscaling_groups":         dataSourceAwsAutoscalingGroups(),
-			"aws_availability_zone":          dataSourceAwsAvailabilityZone(),
-			"aws_availability_zones":         dataSourceAwsAvailabilityZones(),
-			"aws_billing_service_account":    dataSourceAwsBillingServiceAccount(),
-			"aws_caller_identity":            dataSourceAwsCallerIdentity(),
-			"aws_canonical_user_id":          dataSourceAwsCanonicalUserId(),
-			"aws_cloudformation_stack":       dataSourceAwsCloudFormationStack(),
-			"aws_cloudtrail_service_account": dataSourceAwsCloudTrailServiceAccount(),
-			"aws_db_instance":                dataSourceAwsDbInstance(),
-			"aws_db_snapshot":                dataSourceAwsDbSnapshot(),
-			"aws_dynamodb_table":             dataSourceAwsDynamoDbTable(),
-			"aws_ebs_snapshot":               dataSourceAwsEbsSnapshot(),
-			"aws_ebs_snapshot_ids":           dataSourceAwsEbsSnapshotIds(),
-			"aws_ebs_volume":                 dataSourceAwsEbsVolume(),
-			"aws_ecr_repository":             dataSourceAwsEcrRepository(),
-			"aws_ecs_cluster":                dataSourceAwsEcsCluster(),
-			"aws_ecs_container_definition":   dataSourceAwsEcsContainerDefinition(),
-			"aws_ecs_task_definition":        dataSourceAwsEcsTaskDefinition(),
-			"aws_efs_file_system":            dataSourceAwsEfsFileSystem(),
-			"aws_efs_mount_target":           dataSourceAwsEfsMountTarget(),
-			"aws_eip":                        dataSourceAwsEip(),
-			"aws_elastic_beanstalk_solution_stack": dataSourceAwsElasticBeanstalkSolutionStack(),
-			"aws_elasticache_cluster":              dataSourceAwsElastiCacheCluster(),
-			"aws_elb":                              dataSourceAwsElb(),
-			"aws_elasticache_replication_group":    dataSourceAwsElasticacheReplicationGroup(),
-			"aws_elb_hosted_zone_id":               dataSourceAwsElbHostedZoneId(),
-			"aws_elb_service_account":              dataSourceAwsElbServiceAccount(),
-			"aws_iam_account_alias":                dataSourceAwsIamAccountAlias(),
-			"aws_iam_group":                        dataSourceAwsIAMGroup(),
-			"aws_iam_instance_profile":             dataSourceAwsIAMInstanceProfile(),
-			"aws_iam_policy_document":              dataSourceAwsIamPolicyDocument(),
-			"aws_iam_role":                         dataSourceAwsIAMRole(),
-			"aws_iam_server_certificate":           dataSourceAwsIAMServerCertificate(),
-			"aws_iam_user":                         dataSourceAwsIAMUser(),
-			"aws_internet_gateway":                 dataSourceAwsInternetGateway(),
-			"aws_instance":                         dataSourceAwsInstance(),
-			"aws_instances":                        dataSourceAwsInstances(),
-			"aws_ip_ranges":                        dataSourceAwsIPRanges(),
-			"aws_kinesis_stream":                   dataSourceAwsKinesisStream(),
-			"aws_kms_alias":                        dataSourceAwsKmsAlias(),
-			"aws_kms_ciphertext":                   dataSourceAwsKmsCiphertext(),
-			"aws_kms_secret":                       dataSourceAwsKmsSecret(),
-			"aws_nat_gateway":                      dataSourceAwsNatGateway(),
-			"aws_network_interface":                dataSourceAwsNetworkInterface(),
-			"aws_partition":                        dataSourceAwsPartition(),
-			"aws_prefix_list":                      dataSourceAwsPrefixList(),
-			"aws_rds_cluster":                      dataSourceAwsRdsCluster(),
-			"aws_redshift_service_account":         dataSourceAwsRedshiftServiceAccount(),
-			"aws_region":                           dataSourceAwsRegion(),
-			"aws_route_table":                      dataSourceAwsRouteTable(),
-			"aws_route53_zone":                     dataSourceAwsRoute53Zone(),
-			"aws_s3_bucket":                        dataSourceAwsS3Bucket(),
-			"aws_s3_bucket_object":                 dataSourceAwsS3BucketObject(),
-			"aws_sns_topic":                        dataSourceAwsSnsTopic(),
-			"aws_ssm_parameter":                    dataSourceAwsSsmParameter(),
-			"aws_subnet":                           dataSourceAwsSubnet(),
-			"aws_subnet_ids":                       dataSourceAwsSubnetIDs(),
-			"aws_security_group":                   dataSourceAwsSecurityGroup(),
-			"aws_vpc":                              dataSourceAwsVpc(),
-			"aws_vpc_endpoint":                     dataSourceAwsVpcEndpoint(),
-			"aws_vpc_endpoint_service":             dataSourceAwsVpcEndpointService(),
-			"aws_vpc_peering_connection":           dataSourceAwsVpcPeeringConnection(),
-			"aws_vpn_gateway":                      dataSourceAwsVpnGateway(),
+			"aws_acm_certificate":                    dataSourceAwsAcmCertificate(),
+			"aws_ami":                                dataSourceAwsAmi(),
+			"aws_ami_ids":                            dataSourceAwsAmiIds(),
+			"aws_autoscaling_groups":                 dataSourceAwsAutoscalingGroups(),
+			"aws_availability_zone":                  dataSourceAwsAvailabilityZone(),
+			"aws_availability_zones":                 dataSourceAwsAvailabilityZones(),
+			"aws_billing_service_account":            dataSourceAwsBillingServiceAccount(),
+			"aws_caller_identity":                    dataSourceAwsCallerIdentity(),
+			"aws_canonical_user_id":                  dataSourceAwsCanonicalUserId(),
+			"aws_cloudformation_stack":               dataSourceAwsCloudFormationStack(),
+			"aws_cloudformation_stack_set":           dataSourceAwsCloudFormationStackSet(),
+			"aws_cloudformation_stack_set_instance":  dataSourceAwsCloudFormationStackSetInstance(),
+			"aws_cloudformation_stack_set_instances": dataSourceAwsCloudFormationStackSetInstances(),
+			"aws_cloudformation_stack_sets":          dataSourceAwsCloudFormationStackSets(),
+			"aws_cloudtrail_service_account":         dataSourceAwsCloudTrailServiceAccount(),
+			"aws_db_instance":                        dataSourceAwsDbInstance(),
+			"aws_db_snapshot":                        dataSourceAwsDbSnapshot(),
+			"aws_dynamodb_table":                     dataSourceAwsDynamoDbTable(),
+			"aws_ebs_snapshot":                       dataSourceAwsEbsSnapshot(),
+			"aws_ebs_snapshot_ids":                   dataSourceAwsEbsSnapshotIds(),
+			"aws_ebs_volume":                         dataSourceAwsEbsVolume(),
+			"aws_ecr_repository":                     dataSourceAwsEcrRepository(),
+			"aws_ecs_cluster":                        dataSourceAwsEcsCluster(),
+			"aws_ecs_container_definition":           dataSourceAwsEcsContainerDefinition(),
+			"aws_ecs_task_definition":                dataSourceAwsEcsTaskDefinition(),
+			"aws_efs_file_system":                    dataSourceAwsEfsFileSystem(),
+			"aws_efs_mount_target":                   dataSourceAwsEfsMountTarget(),
+			"aws_eip":                                dataSourceAwsEip(),
+			"aws_elastic_beanstalk_solution_stack":   dataSourceAwsElasticBeanstalkSolutionStack(),
+			"aws_elasticache_cluster":                dataSourceAwsElastiCacheCluster(),
+			"aws_elb":                                dataSourceAwsElb(),
+			"aws_elasticache_replication_group":      dataSourceAwsElasticacheReplicationGroup(),
+			"aws_elb_hosted_zone_id":                 dataSourceAwsElbHostedZoneId(),
+			"aws_elb_service_account":                dataSourceAwsElbServiceAccount(),
+			"aws_iam_account_alias":                  dataSourceAwsIamAccountAlias(),
+			"aws_iam_group":                          dataSourceAwsIAMGroup(),
+			"aws_iam_instance_profile":               dataSourceAwsIAMInstanceProfile(),
+			"aws_iam_policy_document":                dataSourceAwsIamPolicyDocument(),
+			"aws_iam_role":                           dataSourceAwsIAMRole(),
+			"aws_iam_server_certificate":             dataSourceAwsIAMServerCertificate(),
+			"aws_iam_user":                           dataSourceAwsIAMUser(),
+			"aws_internet_gateway":                   dataSourceAwsInternetGateway(),
+			"aws_instance":                           dataSourceAwsInstance(),
+			"aws_instances":                          dataSourceAwsInstances(),
+			"aws_ip_ranges":                          dataSourceAwsIPRanges(),
+			"aws_kinesis_stream":                     dataSourceAwsKinesisStream(),
+			"aws_kms_alias":                          dataSourceAwsKmsAlias(),
+			"aws_kms_ciphertext":                     dataSourceAwsKmsCiphertext(),
+			"aws_kms_secret":                         dataSourceAwsKmsSecret(),
+			"aws_nat_gateway":                        dataSourceAwsNatGateway(),
+			"aws_network_interface":                  dataSourceAwsNetworkInterface(),
+			"aws_partition":                          dataSourceAwsPartition(),
+			"aws_prefix_list":                        dataSourceAwsPrefixList(),
+			"aws_rds_cluster":                        dataSourceAwsRdsCluster(),
+			"aws_redshift_service_account":           dataSourceAwsRedshiftServiceAccount(),
+			"aws_region":                             dataSourceAwsRegion(),
+			"aws_route_table":                        dataSourceAwsRouteTable(),
+			"aws_route53_zone":                       dataSourceAwsRoute53Zone(),
+			"aws_s3_bucket":                          dataSourceAwsS3Bucket(),
+			"aws_s3_bucket_object":                   dataSourceAwsS3BucketObject(),
+			"aws_sns_topic":                          dataSourceAwsSnsTopic(),
+			"aws_ssm_parameter":                      dataSourceAwsSsmParameter(),
+			"aws_subnet":                             dataSourceAwsSubnet(),
+			"aws_subnet_ids":                         dataSourceAwsSubnetIDs(),
+			"aws_security_group":                     dataSourceAwsSecurityGroup(),
+			"aws_vpc":                                dataSourceAwsVpc(),
+			"aws_vpc_endpoint":                       dataSourceAwsVpcEndpoint(),
+			"aws_vpc_endpoint_service":               dataSourceAwsVpcEndpointService(),
+			"aws_vpc_peering_connection":             dataSourceAwsVpcPeeringConnection(),
+			"aws_vpn_gateway":                        dataSourceAwsVpnGateway(),
 
 			// Adding the Aliases for the ALB -> LB Rename
 			"aws_lb":               dataSourceAwsLb(),
@@ -273,6 +277,9 @@ func Provider() terraform.ResourceProvider {
 			"aws_autoscaling_policy":                       resourceAwsAutoscalingPolicy(),
 			"aws_autoscaling_schedule":                     resourceAwsAutoscalingSchedule(),
 			"aws_cloudformation_stack":                     resourceAwsCloudFormationStack(),
+			"aws_cloudformation_stack_set":                 resourceAwsCloudFormationStackSet(),
+			"aws_cloudformation_stack_instances":           resourceAwsCloudFormationStackInstances(),
+			"aws_cloudformation_stack_set_instance":        resourceAwsCloudFormationStackSetInstance(),
 			"aws_cloudfront_distribution":                  resourceAwsCloudFrontDistribution(),
 			"aws_cloudfront_origin_access_identity":        resourceAwsCloudFrontOriginAccessIdentity(),
 			"aws_cloudtrail":                               resourceAwsCloudTrail(),
@@ -0,0 +1,519 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceAwsCloudFormationStackInstances() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsCloudFormationStackInstancesCreate,
+		Read:   resourceAwsCloudFormationStackInstancesRead,
+		Update: resourceAwsCloudFormationStackInstancesUpdate,
+		Delete: resourceAwsCloudFormationStackInstancesDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		CustomizeDiff: resourceAwsCloudFormationStackInstancesCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"stack_set_name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"accounts": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validateAwsAccountId,
+				},
+				Set: schema.HashString,
+			},
+			"regions": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"wait_for_current": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"operation_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"operation_conflict_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"stop_operation_on_timeout": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"retain_stacks": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"deployment_window": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateOnceADayWindowFormat,
+			},
+			"operation_preferences": cloudFormationStackSetOperationPreferencesSchema(),
+			"stack_instances": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"account_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"region": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"stack_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceAwsCloudFormationStackInstancesCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName := d.Get("stack_set_name").(string)
+	accounts := d.Get("accounts").(*schema.Set)
+	regions := d.Get("regions").(*schema.Set)
+
+	// All instances are created by a single stack set operation, which
+	// deploys to every region in each of the accounts
+	operationId := resource.UniqueId()
+	input := &cloudformation.CreateStackInstancesInput{
+		StackSetName: aws.String(stackSetName),
+		Accounts:     expandStringList(accounts.List()),
+		Regions:      expandStringList(regions.List()),
+		OperationId:  aws.String(operationId),
+	}
+	input.OperationPreferences = expandCloudFormationStackSetOperationPreferences(d.Get("operation_preferences").([]interface{}))
+
+	timeout, err := waitForCloudFormationDeploymentWindow(d, d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	log.Printf("[DEBUG] Creating CloudFormation stack set instances: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.CreateStackInstances(input)
+		return err
+	})
+	if err != nil {
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			return fmt.Errorf("Creating CloudFormation stack set instances failed: %s", err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+	}
+
+	d.SetId(stackSetName)
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, "create", timeout-time.Since(start),
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	// The operation succeeds as long as the failed instances stay within its
+	// failure tolerance, which leaves them OUTDATED
+	if d.Get("wait_for_current").(bool) {
+		if err := waitForCloudFormationStackInstancesCurrent(conn, stackSetName, accounts, regions, timeout-time.Since(start)); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[INFO] CloudFormation stack set instances of %q created", d.Id())
+
+	return resourceAwsCloudFormationStackInstancesRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackInstancesRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	instances, err := listCloudFormationStackSetInstances(conn, d.Id())
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			log.Printf("[WARN] Removing CloudFormation stack set instances of %s as the stack set is already gone", d.Id())
+			d.SetId("")
+			return nil
+		}
+
+		return err
+	}
+
+	// An imported resource has no accounts and regions yet and adopts all
+	// instances of the stack set
+	accounts := d.Get("accounts").(*schema.Set)
+	regions := d.Get("regions").(*schema.Set)
+	if accounts.Len() == 0 && regions.Len() == 0 {
+		for _, instance := range instances {
+			accounts.Add(aws.StringValue(instance.Account))
+			regions.Add(aws.StringValue(instance.Region))
+		}
+		if err := d.Set("accounts", accounts); err != nil {
+			return err
+		}
+		if err := d.Set("regions", regions); err != nil {
+			return err
+		}
+	}
+
+	// Only the instances which exist are recorded, so the plan recreates
+	// those deleted outside of Terraform
+	stackInstances := flattenCloudFormationStackInstances(instances, accounts, regions)
+	if len(stackInstances) == 0 {
+		log.Printf("[WARN] Removing CloudFormation stack set instances of %s as they're already gone", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("stack_set_name", d.Id())
+	if err := d.Set("stack_instances", stackInstances); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func resourceAwsCloudFormationStackInstancesUpdate(d *schema.ResourceData, meta interface{}) error {
+	// retain_stacks is only used on delete, the other arguments by the
+	// operations changing the instances. Missing instances are planned as a
+	// change of stack_instances.
+	if !d.HasChange("accounts") && !d.HasChange("regions") && !d.HasChange("stack_instances") {
+		return resourceAwsCloudFormationStackInstancesRead(d, meta)
+	}
+
+	conn := meta.(*AWSClient).cfconn
+
+	o, n := d.GetChange("accounts")
+	oldAccounts, accounts := o.(*schema.Set), n.(*schema.Set)
+	o, n = d.GetChange("regions")
+	oldRegions, regions := o.(*schema.Set), n.(*schema.Set)
+
+	instances, err := listCloudFormationStackSetInstances(conn, d.Id())
+	if err != nil {
+		return fmt.Errorf("Listing CloudFormation stack set %q instances failed: %s", d.Id(), err)
+	}
+
+	// Only the instances which are no longer targeted are deleted and only
+	// the missing ones created, all others are left untouched
+	creates, deletes := cloudFormationStackInstancesDelta(instances, oldAccounts, oldRegions, accounts, regions)
+	preferences := expandCloudFormationStackSetOperationPreferences(d.Get("operation_preferences").([]interface{}))
+	conflictTimeout := cloudFormationStackSetOperationConflictTimeout(d)
+	operationTimeout := cloudFormationStackSetOperationTimeout(d)
+	stopOnTimeout := d.Get("stop_operation_on_timeout").(bool)
+
+	// Only waiting for the window when there are operations to start
+	timeout := d.Timeout(schema.TimeoutUpdate)
+	if len(creates) > 0 || len(deletes) > 0 {
+		timeout, err = waitForCloudFormationDeploymentWindow(d, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+
+	if len(creates) > 0 {
+		if err := createCloudFormationStackSetInstances(conn, d.Id(), creates, preferences, conflictTimeout,
+			operationTimeout, stopOnTimeout, timeout-time.Since(start)); err != nil {
+			return err
+		}
+	}
+	if len(deletes) > 0 {
+		retainStacks := d.Get("retain_stacks").(bool)
+		if err := deleteCloudFormationStackSetInstances(conn, d.Id(), deletes, retainStacks, preferences, conflictTimeout,
+			operationTimeout, stopOnTimeout, "update", timeout-time.Since(start)); err != nil {
+			return err
+		}
+	}
+
+	if d.Get("wait_for_current").(bool) {
+		if err := waitForCloudFormationStackInstancesCurrent(conn, d.Id(), accounts, regions, timeout-time.Since(start)); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[INFO] CloudFormation stack set instances of %q updated", d.Id())
+
+	return resourceAwsCloudFormationStackInstancesRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackInstancesDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	operationId := resource.UniqueId()
+	input := &cloudformation.DeleteStackInstancesInput{
+		StackSetName: aws.String(d.Id()),
+		Accounts:     expandStringList(d.Get("accounts").(*schema.Set).List()),
+		Regions:      expandStringList(d.Get("regions").(*schema.Set).List()),
+		OperationId:  aws.String(operationId),
+		RetainStacks: aws.Bool(d.Get("retain_stacks").(bool)),
+	}
+	input.OperationPreferences = expandCloudFormationStackSetOperationPreferences(d.Get("operation_preferences").([]interface{}))
+
+	timeout, err := waitForCloudFormationDeploymentWindow(d, d.Timeout(schema.TimeoutDelete))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Deleting CloudFormation stack set instances: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.DeleteStackInstances(input)
+		return err
+	})
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			return nil
+		}
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			return fmt.Errorf("Deleting CloudFormation stack set instances of %q failed: %s", d.Id(), err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", d.Id(), operationId, err)
+	}
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, d.Id(), operationId, "delete", timeout,
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] CloudFormation stack set instances of %q have been deleted", d.Id())
+
+	return nil
+}
+
+func resourceAwsCloudFormationStackInstancesCustomizeDiff(diff *schema.ResourceDiff, meta interface{}) error {
+	if err := validateCloudFormationStackSetOperationPreferences(diff.Get("operation_preferences").([]interface{})); err != nil {
+		return err
+	}
+
+	// Refreshing only records the instances which exist, so an instance
+	// deleted outside of Terraform is missing from stack_instances without
+	// accounts or regions changing. Updating the resource creates it again.
+	if diff.Id() == "" || !diff.NewValueKnown("accounts") || !diff.NewValueKnown("regions") {
+		return nil
+	}
+	missing := cloudFormationStackInstancesMissing(diff.Get("stack_instances").([]interface{}),
+		diff.Get("accounts").(*schema.Set), diff.Get("regions").(*schema.Set))
+	if len(missing) > 0 {
+		log.Printf("[WARN] CloudFormation stack set %q instances are missing: %s", diff.Id(), strings.Join(missing, ", "))
+		return diff.SetNewComputed("stack_instances")
+	}
+
+	return nil
+}
+
+// cloudFormationStackInstancesMissing returns the accounts and regions
+// targeted by the resource which have no stack instance, sorted
+func cloudFormationStackInstancesMissing(stackInstances []interface{}, accounts, regions *schema.Set) []string {
+	existing := make(map[string]bool)
+	for _, v := range stackInstances {
+		instance := v.(map[string]interface{})
+		existing[fmt.Sprintf("%s (%s)", instance["account_id"], instance["region"])] = true
+	}
+
+	var missing []string
+	for _, account := range accounts.List() {
+		for _, region := range regions.List() {
+			id := fmt.Sprintf("%s (%s)", account, region)
+			if !existing[id] {
+				missing = append(missing, id)
+			}
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// cloudFormationStackInstancesDelta returns the instances to create for the
+// new accounts and regions and the existing ones of the old accounts and
+// regions to delete
+func cloudFormationStackInstancesDelta(instances []*cloudformation.StackInstanceSummary, oldAccounts, oldRegions, accounts, regions *schema.Set) (creates, deletes []*cloudformation.StackInstanceSummary) {
+	existing := make(map[string]bool)
+	for _, instance := range instances {
+		account := aws.StringValue(instance.Account)
+		region := aws.StringValue(instance.Region)
+		existing[account+","+region] = true
+
+		if oldAccounts.Contains(account) && oldRegions.Contains(region) && !(accounts.Contains(account) && regions.Contains(region)) {
+			deletes = append(deletes, instance)
+		}
+	}
+
+	for _, account := range accounts.List() {
+		for _, region := range regions.List() {
+			if !existing[account.(string)+","+region.(string)] {
+				creates = append(creates, &cloudformation.StackInstanceSummary{
+					Account: aws.String(account.(string)),
+					Region:  aws.String(region.(string)),
+				})
+			}
+		}
+	}
+
+	return creates, deletes
+}
+
+// createCloudFormationStackSetInstances creates the given instances of the
+// stack set within the timeout, grouping the accounts by their regions like
+// deleteCloudFormationStackSetInstances
+func createCloudFormationStackSetInstances(conn *cloudformation.CloudFormation, stackSetName string, instances []*cloudformation.StackInstanceSummary,
+	preferences *cloudformation.StackSetOperationPreferences, conflictTimeout, operationTimeout time.Duration, stopOnTimeout bool, timeout time.Duration) error {
+	start := time.Now()
+	for _, target := range cloudFormationStackSetInstanceTargets(instances) {
+		operationId := resource.UniqueId()
+		input := &cloudformation.CreateStackInstancesInput{
+			StackSetName:         aws.String(stackSetName),
+			Accounts:             aws.StringSlice(target.Accounts),
+			Regions:              aws.StringSlice(target.Regions),
+			OperationId:          aws.String(operationId),
+			OperationPreferences: preferences,
+		}
+
+		log.Printf("[DEBUG] Creating CloudFormation stack set instances: %s", input)
+		err := retryCloudFormationStackSetOperation(conn, conflictTimeout, func() error {
+			_, err := conn.CreateStackInstances(input)
+			return err
+		})
+		if err != nil {
+			if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+				return fmt.Errorf("Creating CloudFormation stack set %q instances failed: %s", stackSetName, err)
+			}
+			log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+		}
+
+		if err := waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, "update", timeout-time.Since(start), operationTimeout, stopOnTimeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForCloudFormationStackInstancesCurrent blocks until the instances of
+// all accounts and regions are CURRENT and fails early on INOPERABLE ones
+func waitForCloudFormationStackInstancesCurrent(conn *cloudformation.CloudFormation, stackSetName string, accounts, regions *schema.Set, timeout time.Duration) error {
+	var lagging []string
+	wait := resource.StateChangeConf{
+		Pending: []string{cloudformation.StackInstanceStatusOutdated},
+		Target:  []string{cloudformation.StackInstanceStatusCurrent},
+		Timeout: timeout,
+		Refresh: func() (interface{}, string, error) {
+			instances, err := listCloudFormationStackSetInstances(conn, stackSetName)
+			if err != nil {
+				return nil, "", err
+			}
+
+			current := make(map[string]bool)
+			lagging = nil
+			for _, instance := range flattenCloudFormationStackInstances(instances, accounts, regions) {
+				id := fmt.Sprintf("%s (%s)", instance["account_id"], instance["region"])
+				switch instance["status"] {
+				case cloudformation.StackInstanceStatusCurrent:
+					current[id] = true
+				case cloudformation.StackInstanceStatusInoperable:
+					return nil, "", fmt.Errorf("CloudFormation stack set %q instance of account %s is %s", stackSetName, id, cloudformation.StackInstanceStatusInoperable)
+				}
+			}
+			for _, account := range accounts.List() {
+				for _, region := range regions.List() {
+					id := fmt.Sprintf("%s (%s)", account, region)
+					if !current[id] {
+						lagging = append(lagging, id)
+					}
+				}
+			}
+
+			if len(lagging) > 0 {
+				sort.Strings(lagging)
+				log.Printf("[DEBUG] Waiting for CloudFormation stack set %q instances to be current: %s", stackSetName, strings.Join(lagging, ", "))
+				return instances, cloudformation.StackInstanceStatusOutdated, nil
+			}
+			return instances, cloudformation.StackInstanceStatusCurrent, nil
+		},
+	}
+
+	if _, err := wait.WaitForState(); err != nil {
+		if _, ok := err.(*resource.TimeoutError); ok {
+			return fmt.Errorf("Timeout waiting for CloudFormation stack set %q instances to be current, still outdated: %s", stackSetName, strings.Join(lagging, ", "))
+		}
+		return err
+	}
+	return nil
+}
+
+// flattenCloudFormationStackInstances returns the instances deployed to the
+// given accounts and regions, sorted by account and region. Instances of
+// the stack set in other accounts or regions are managed elsewhere.
+// Without accounts and regions, all instances are returned.
+func flattenCloudFormationStackInstances(instances []*cloudformation.StackInstanceSummary, accounts, regions *schema.Set) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(instances))
+	for _, instance := range instances {
+		accountId := aws.StringValue(instance.Account)
+		region := aws.StringValue(instance.Region)
+		if (accounts != nil && !accounts.Contains(accountId)) || (regions != nil && !regions.Contains(region)) {
+			continue
+		}
+
+		result = append(result, map[string]interface{}{
+			"account_id": accountId,
+			"region":     region,
+			"stack_id":   aws.StringValue(instance.StackId),
+			"status":     aws.StringValue(instance.Status),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i]["account_id"] != result[j]["account_id"] {
+			return result[i]["account_id"].(string) < result[j]["account_id"].(string)
+		}
+		return result[i]["region"].(string) < result[j]["region"].(string)
+	})
+
+	return result
+}
@@ -0,0 +1,538 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceAwsCloudFormationStackInstancesCreate_accounts(t *testing.T) {
+	accounts := []string{"111111111111", "123456789012", "210987654321"}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"CreateStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationCreateStackInstancesResponse, ContentType: "text/xml"},
+		},
+		"DescribeStackSetOperation": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesAccountsResponse(append(accounts, "999999999999")...), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"accounts":       []interface{}{accounts[2], accounts[0], accounts[1]},
+		"regions":        []interface{}{"us-east-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &AWSClient{cfconn: conn}
+	diff, err := r.Diff(nil, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := r.Apply(nil, diff, meta)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	creates := requests["CreateStackInstances"]
+	if len(creates) != 1 {
+		t.Fatalf("Expected a single CreateStackInstances request, received %d", len(creates))
+	}
+	var sent []string
+	for i := 1; creates[0].Get(fmt.Sprintf("Accounts.member.%d", i)) != ""; i++ {
+		sent = append(sent, creates[0].Get(fmt.Sprintf("Accounts.member.%d", i)))
+	}
+	sort.Strings(sent)
+	if !reflect.DeepEqual(sent, accounts) {
+		t.Fatalf("Expected accounts %v to be sent, received: %v", accounts, sent)
+	}
+	if v := creates[0].Get("Regions.member.1"); v != "us-east-1" || creates[0].Get("Regions.member.2") != "" {
+		t.Fatalf("Expected only region us-east-1 to be sent, received: %v", creates[0])
+	}
+
+	if state.ID != "tf-test" {
+		t.Fatalf("Expected ID to be the stack set name, received: %q", state.ID)
+	}
+	if v := state.Attributes["stack_instances.#"]; v != fmt.Sprintf("%d", len(accounts)) {
+		t.Fatalf("Expected %d stack instances, excluding other accounts, received %s", len(accounts), v)
+	}
+	for i, account := range accounts {
+		if v := state.Attributes[fmt.Sprintf("stack_instances.%d.account_id", i)]; v != account {
+			t.Fatalf("Expected stack instance %d to be in account %s, received: %s", i, account, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackInstancesCreate_retriedOperationId(t *testing.T) {
+	// The first request reaches CloudFormation but the connection drops
+	// before its response, so the SDK retries it with the same operation ID
+	var mu sync.Mutex
+	var operationIds []string
+	closeFunc, sess, err := getMockedAwsApiSessionWithResponder("CloudFormation", func(r *http.Request, requestBody string) *awsMockResponse {
+		params, _ := url.ParseQuery(requestBody)
+		switch params.Get("Action") {
+		case "CreateStackInstances":
+			mu.Lock()
+			operationIds = append(operationIds, params.Get("OperationId"))
+			n := len(operationIds)
+			mu.Unlock()
+			if n == 1 {
+				panic(http.ErrAbortHandler)
+			}
+			return &awsMockResponse{400, testCloudFormationErrorResponse(cloudformation.ErrCodeOperationIdAlreadyExistsException,
+				"The specified operation ID already exists."), "text/xml"}
+		case "DescribeStackSetOperation":
+			return &awsMockResponse{200, testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), "text/xml"}
+		case "ListStackInstances":
+			return &awsMockResponse{200, testCloudFormationListStackInstancesAccountsResponse("123456789012"), "text/xml"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"accounts":       []interface{}{"123456789012"},
+		"regions":        []interface{}{"us-east-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &AWSClient{cfconn: cloudformation.New(sess)}
+	diff, err := r.Diff(nil, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := r.Apply(nil, diff, meta)
+	if err != nil {
+		t.Fatalf("Expected the already started operation to be waited for, received: %s", err)
+	}
+	if state.ID != "tf-test" {
+		t.Fatalf("Expected ID to be the stack set name, received: %q", state.ID)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(operationIds) != 2 {
+		t.Fatalf("Expected the dropped CreateStackInstances request to be retried once, received %d requests", len(operationIds))
+	}
+	if operationIds[0] == "" || operationIds[0] != operationIds[1] {
+		t.Fatalf("Expected the retry to reuse the operation ID, received: %q", operationIds)
+	}
+}
+
+func TestResourceAwsCloudFormationStackInstancesUpdate_delta(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackInstances": {
+			// 999999999999 is managed elsewhere
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesStatusResponse(
+				"111111111111", "us-east-1", "CURRENT",
+				"123456789012", "us-east-1", "CURRENT",
+				"999999999999", "us-east-1", "CURRENT",
+			), ContentType: "text/xml"},
+		},
+		"CreateStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationCreateStackInstancesResponse, ContentType: "text/xml"},
+		},
+		"DeleteStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationDeleteStackInstancesResponse, ContentType: "text/xml"},
+		},
+		"DescribeStackSetOperation": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	old := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"accounts":       []interface{}{"111111111111", "123456789012"},
+		"regions":        []interface{}{"us-east-1"},
+	})
+	old.SetId("tf-test")
+	state := old.State()
+
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"accounts":       []interface{}{"123456789012", "210987654321"},
+		"regions":        []interface{}{"us-east-1", "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &AWSClient{cfconn: conn}
+	diff, err := r.Diff(state, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.RequiresNew() {
+		t.Fatal("Expected accounts and regions to be updated in place")
+	}
+	if _, err := r.Apply(state, diff, meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	// One operation per set of regions, only for the missing instances
+	creates := requests["CreateStackInstances"]
+	if len(creates) != 2 {
+		t.Fatalf("Expected two CreateStackInstances requests, received %d", len(creates))
+	}
+	expectedCreates := []map[string]string{
+		{"Accounts.member.1": "123456789012", "Regions.member.1": "eu-west-1", "Regions.member.2": ""},
+		{"Accounts.member.1": "210987654321", "Regions.member.1": "eu-west-1", "Regions.member.2": "us-east-1"},
+	}
+	for i, expected := range expectedCreates {
+		for k, v := range expected {
+			if actual := creates[i].Get(k); actual != v || creates[i].Get("Accounts.member.2") != "" {
+				t.Fatalf("Expected CreateStackInstances request %d %s to be %q, received: %v", i, k, v, creates[i])
+			}
+		}
+	}
+
+	deletes := requests["DeleteStackInstances"]
+	if len(deletes) != 1 {
+		t.Fatalf("Expected a single DeleteStackInstances request, received %d", len(deletes))
+	}
+	if deletes[0].Get("Accounts.member.1") != "111111111111" || deletes[0].Get("Accounts.member.2") != "" ||
+		deletes[0].Get("Regions.member.1") != "us-east-1" || deletes[0].Get("Regions.member.2") != "" {
+		t.Fatalf("Expected only the instance of 111111111111 in us-east-1 to be deleted, received: %v", deletes[0])
+	}
+}
+
+func TestResourceAwsCloudFormationStackInstancesUpdate_drift(t *testing.T) {
+	// The instance of 123456789012 was deleted outside of Terraform
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesAccountsResponse("111111111111"), ContentType: "text/xml"},
+		},
+		"CreateStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationCreateStackInstancesResponse, ContentType: "text/xml"},
+		},
+		"DescribeStackSetOperation": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	configRaw := map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"accounts":       []interface{}{"111111111111", "123456789012"},
+		"regions":        []interface{}{"us-east-1"},
+	}
+	old := schema.TestResourceDataRaw(t, r.Schema, configRaw)
+	old.SetId("tf-test")
+	meta := &AWSClient{cfconn: conn}
+	if err := resourceAwsCloudFormationStackInstancesRead(old, meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	state := old.State()
+
+	rawConfig, err := config.NewRawConfig(configRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := r.Diff(state, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Empty() || diff.RequiresNew() {
+		t.Fatalf("Expected the missing instance to be planned as an update, received: %#v", diff)
+	}
+	if _, err := r.Apply(state, diff, meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	creates := requests["CreateStackInstances"]
+	if len(creates) != 1 {
+		t.Fatalf("Expected a single CreateStackInstances request, received %d", len(creates))
+	}
+	if creates[0].Get("Accounts.member.1") != "123456789012" || creates[0].Get("Accounts.member.2") != "" ||
+		creates[0].Get("Regions.member.1") != "us-east-1" || creates[0].Get("Regions.member.2") != "" {
+		t.Fatalf("Expected only the missing instance to be created, received: %v", creates[0])
+	}
+	if n := len(requests["DeleteStackInstances"]); n != 0 {
+		t.Fatalf("Expected no DeleteStackInstances requests, received %d", n)
+	}
+}
+
+func TestResourceAwsCloudFormationStackInstancesImport(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesStatusResponse(
+				"111111111111", "us-east-1", "CURRENT",
+				"111111111111", "eu-west-1", "CURRENT",
+				"123456789012", "us-east-1", "OUTDATED",
+			), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	d := r.Data(nil)
+	d.SetId("tf-test")
+	meta := &AWSClient{cfconn: conn}
+
+	results, err := r.Importer.State(d, meta)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected a single imported resource, received %d", len(results))
+	}
+	if err := resourceAwsCloudFormationStackInstancesRead(results[0], meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if v := results[0].Get("stack_set_name").(string); v != "tf-test" {
+		t.Fatalf("Expected stack_set_name to be the imported ID, received: %q", v)
+	}
+	expected := map[string][]interface{}{
+		"accounts": {"111111111111", "123456789012"},
+		"regions":  {"eu-west-1", "us-east-1"},
+	}
+	for k, v := range expected {
+		if actual := results[0].Get(k).(*schema.Set); !actual.Equal(schema.NewSet(schema.HashString, v)) {
+			t.Fatalf("Expected the %s of all instances %v, received: %v", k, v, actual.List())
+		}
+	}
+	if v := results[0].Get("stack_instances.#").(int); v != 3 {
+		t.Fatalf("Expected all 3 stack instances, received %d", v)
+	}
+
+	// 123456789012 has no instance in eu-west-1, which is planned to be created
+	missing := cloudFormationStackInstancesMissing(results[0].Get("stack_instances").([]interface{}),
+		results[0].Get("accounts").(*schema.Set), results[0].Get("regions").(*schema.Set))
+	if !reflect.DeepEqual(missing, []string{"123456789012 (eu-west-1)"}) {
+		t.Fatalf("Expected the instance of 123456789012 in eu-west-1 to be missing, received: %q", missing)
+	}
+}
+
+func TestResourceAwsCloudFormationStackInstances_deploymentWindow(t *testing.T) {
+	now := time.Now().UTC()
+	later := fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04"))
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackInstances()
+	configRaw := map[string]interface{}{
+		"stack_set_name":    "tf-test",
+		"accounts":          []interface{}{"123456789012"},
+		"regions":           []interface{}{"us-east-1"},
+		"deployment_window": later,
+	}
+	rawConfig, err := config.NewRawConfig(configRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := &AWSClient{cfconn: conn}
+	diff, err := r.Diff(nil, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The window opens after the default timeouts of 30 minutes
+	if _, err := r.Apply(nil, diff, meta); err == nil {
+		t.Fatalf("Expected creating outside of window %q to fail", later)
+	}
+	d := schema.TestResourceDataRaw(t, r.Schema, configRaw)
+	d.SetId("tf-test")
+	if _, err := r.Apply(d.State(), &terraform.InstanceDiff{Destroy: true}, meta); err == nil {
+		t.Fatalf("Expected deleting outside of window %q to fail", later)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("Expected no operation to be started outside of the window, received: %v", requests)
+	}
+}
+
+func TestCloudFormationStackSetInstances_operationTimeout(t *testing.T) {
+	instances := []*cloudformation.StackInstanceSummary{
+		{Account: aws.String("123456789012"), Region: aws.String("us-east-1")},
+	}
+
+	for _, action := range []string{"create", "delete"} {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"CreateStackInstances": {
+				{StatusCode: 200, Body: testCloudFormationCreateStackInstancesResponse, ContentType: "text/xml"},
+			},
+			"DeleteStackInstances": {
+				{StatusCode: 200, Body: testCloudFormationDeleteStackInstancesResponse, ContentType: "text/xml"},
+			},
+			"DescribeStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusRunning), ContentType: "text/xml"},
+			},
+			"StopStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationStopStackSetOperationResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if action == "create" {
+			err = createCloudFormationStackSetInstances(conn, "tf-test", instances, nil, 0, 500*time.Millisecond, true, 30*time.Minute)
+		} else {
+			err = deleteCloudFormationStackSetInstances(conn, "tf-test", instances, false, nil, 0, 500*time.Millisecond, true, "delete", 30*time.Minute)
+		}
+		closeFunc()
+		if err == nil || !strings.Contains(err.Error(), "did not finish within 500ms") {
+			t.Fatalf("%s: Expected the operation timeout to be exceeded, received: %v", action, err)
+		}
+		if n := len(requests["StopStackSetOperation"]); n != 1 {
+			t.Fatalf("%s: Expected the operation to be stopped, received %d StopStackSetOperation requests", action, n)
+		}
+	}
+}
+
+func TestWaitForCloudFormationStackInstancesCurrent_laggingRegions(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesStatusResponse(
+				"123456789012", "us-east-1", "CURRENT",
+				"123456789012", "eu-west-1", "OUTDATED",
+			), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesStatusResponse(
+				"123456789012", "us-east-1", "CURRENT",
+				"123456789012", "eu-west-1", "CURRENT",
+			), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	accounts := schema.NewSet(schema.HashString, []interface{}{"123456789012"})
+	regions := schema.NewSet(schema.HashString, []interface{}{"us-east-1", "eu-west-1"})
+	if err := waitForCloudFormationStackInstancesCurrent(conn, "tf-test", accounts, regions, time.Minute); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if n := len(requests["ListStackInstances"]); n != 2 {
+		t.Fatalf("Expected to wait for the lagging region, received %d ListStackInstances requests", n)
+	}
+}
+
+func TestWaitForCloudFormationStackInstancesCurrent_timeout(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackInstances": {
+			// The eu-west-1 instance isn't even listed yet
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesStatusResponse(
+				"123456789012", "us-east-1", "OUTDATED",
+			), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	accounts := schema.NewSet(schema.HashString, []interface{}{"123456789012"})
+	regions := schema.NewSet(schema.HashString, []interface{}{"us-east-1", "eu-west-1"})
+	err = waitForCloudFormationStackInstancesCurrent(conn, "tf-test", accounts, regions, 500*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected a timeout error")
+	}
+	if !strings.Contains(err.Error(), "still outdated: 123456789012 (eu-west-1), 123456789012 (us-east-1)") {
+		t.Fatalf("Expected the error to name the lagging instances, received: %s", err)
+	}
+}
+
+const testCloudFormationCreateStackInstancesResponse = `<CreateStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <CreateStackInstancesResult>
+    <OperationId>terraform-20171012000000000000000001</OperationId>
+  </CreateStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</CreateStackInstancesResponse>`
+
+// testCloudFormationListStackInstancesAccountsResponse returns a page with a
+// current stack instance in us-east-1 of each of the accounts
+func testCloudFormationListStackInstancesAccountsResponse(accounts ...string) string {
+	var summaries bytes.Buffer
+	for _, account := range accounts {
+		fmt.Fprintf(&summaries, `
+      <member>
+        <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+        <StackId>arn:aws:cloudformation:us-east-1:%s:stack/StackSet-tf-test-0123/2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackId>
+        <Account>%s</Account>
+        <Region>us-east-1</Region>
+        <Status>CURRENT</Status>
+      </member>`, account, account)
+	}
+
+	return fmt.Sprintf(`<ListStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackInstancesResult>
+    <Summaries>%s
+    </Summaries>
+  </ListStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackInstancesResponse>`, summaries.String())
+}
+
+// testCloudFormationListStackInstancesStatusResponse returns a page of stack
+// instances for the given account, region and status triples
+func testCloudFormationListStackInstancesStatusResponse(instances ...string) string {
+	var summaries bytes.Buffer
+	for i := 0; i < len(instances); i += 3 {
+		fmt.Fprintf(&summaries, `
+      <member>
+        <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+        <Account>%s</Account>
+        <Region>%s</Region>
+        <Status>%s</Status>
+      </member>`, instances[i], instances[i+1], instances[i+2])
+	}
+
+	return fmt.Sprintf(`<ListStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackInstancesResult>
+    <Summaries>%s
+    </Summaries>
+  </ListStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackInstancesResponse>`, summaries.String())
+}
@@ -0,0 +1,1555 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+// The IAM roles all stack set operations use, as they can't be chosen per stack set
+const (
+	cloudFormationStackSetAdministrationRoleName = "AWSCloudFormationStackSetAdministrationRole"
+	cloudFormationStackSetExecutionRoleName      = "AWSCloudFormationStackSetExecutionRole"
+)
+
+func resourceAwsCloudFormationStackSet() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsCloudFormationStackSetCreate,
+		Read:   resourceAwsCloudFormationStackSetRead,
+		Update: resourceAwsCloudFormationStackSetUpdate,
+		Delete: resourceAwsCloudFormationStackSetDelete,
+
+		CustomizeDiff: resourceAwsCloudFormationStackSetCustomizeDiff,
+
+		Importer: &schema.ResourceImporter{
+			State: resourceAwsCloudFormationStackSetImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"administration_role_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"execution_role_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"stack_set_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"template_body": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateCloudFormationStackSetTemplate,
+				StateFunc: func(v interface{}) string {
+					template, _ := normalizeCloudFormationTemplate(v)
+					return template
+				},
+			},
+			"template_body_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"detected_template_format": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"transforms": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"template_url": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				DiffSuppressFunc: suppressUrlQueryDiffs,
+			},
+			"capabilities": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"effective_capabilities": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"parameters": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateCloudFormationParameters,
+			},
+			"stack_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"outdated_instance_count": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"deployed_regions": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"tags": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"effective_instance_tags": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+			"prevent_update": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"warn_unnecessary_capabilities": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"warn_missing_parameters": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"operation_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"operation_conflict_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"stop_operation_on_timeout": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"retain_stacks_on_delete": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"check_running_operations": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"treat_partial_failure_as_error": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"deployment_window": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateOnceADayWindowFormat,
+			},
+			"operation_preferences": cloudFormationStackSetOperationPreferencesSchema(),
+		},
+	}
+}
+
+// cloudFormationStackSetOperationPreferencesSchema returns the schema of the
+// preferences tuning how stack set operations roll out to the instances
+func cloudFormationStackSetOperationPreferencesSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"failure_tolerance_count": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"failure_tolerance_percentage": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntBetween(0, 100),
+				},
+				"max_concurrent_count": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+				"max_concurrent_percentage": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntBetween(1, 100),
+				},
+				"region_order": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+func resourceAwsCloudFormationStackSetCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	name := d.Get("name").(string)
+
+	// The token makes retried requests idempotent
+	input := &cloudformation.CreateStackSetInput{
+		StackSetName:       aws.String(name),
+		ClientRequestToken: aws.String(resource.UniqueId()),
+	}
+	if v, ok := d.GetOk("description"); ok {
+		input.Description = aws.String(v.(string))
+	}
+	if v, ok := d.GetOk("template_body"); ok {
+		template, err := normalizeCloudFormationTemplate(v)
+		if err != nil {
+			return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+		}
+		input.TemplateBody = aws.String(template)
+	}
+	if v, ok := d.GetOk("template_url"); ok {
+		input.TemplateURL = aws.String(v.(string))
+	}
+	if v, ok := d.GetOk("capabilities"); ok {
+		input.Capabilities = expandStringList(v.(*schema.Set).List())
+	}
+	if v, ok := d.GetOk("parameters"); ok {
+		input.Parameters = expandCloudFormationParameters(v.(map[string]interface{}))
+	}
+	if v, ok := d.GetOk("tags"); ok {
+		input.Tags = expandCloudFormationTags(v.(map[string]interface{}))
+	}
+
+	log.Printf("[DEBUG] Creating CloudFormation stack set: %s", input)
+	var stackSetId string
+	err := retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		resp, err := conn.CreateStackSet(input)
+		if err == nil {
+			stackSetId = aws.StringValue(resp.StackSetId)
+		}
+		return err
+	})
+	if err != nil {
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			err = cloudFormationStackSetTemplateUrlError(cloudFormationParameterValueError(err), meta.(*AWSClient).region)
+			return fmt.Errorf("Creating CloudFormation stack set failed: %s", err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q creation already started: %s", name, err)
+
+		// The retried request doesn't return the ID of the stack set it created
+		stackSetId, err = findCloudFormationStackSetActiveId(conn, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The stack set is read by its ID, as a deleted stack set of the same
+	// name may still be described by name
+	d.SetId(name)
+	d.Set("stack_set_id", stackSetId)
+	log.Printf("[INFO] CloudFormation stack set %q (%s) created", name, stackSetId)
+
+	return resourceAwsCloudFormationStackSetRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackSetRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	// Describing the stack set by its ID, once known, never returns a deleted
+	// stack set of the same name
+	input := &cloudformation.DescribeStackSetInput{
+		StackSetName: aws.String(d.Id()),
+	}
+	if v, ok := d.GetOk("stack_set_id"); ok {
+		input.StackSetName = aws.String(v.(string))
+	}
+	resp, err := conn.DescribeStackSet(input)
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			log.Printf("[WARN] Removing CloudFormation stack set %s as it's already gone", d.Id())
+			d.SetId("")
+			return nil
+		}
+
+		return err
+	}
+
+	stackSet := resp.StackSet
+	log.Printf("[DEBUG] Received CloudFormation stack set: %s", stackSet)
+
+	// A deleted stack set is still described for a while, even after another
+	// one was created with the same name, so it is treated as gone to have
+	// it recreated instead of adopting the deleted one
+	if aws.StringValue(stackSet.Status) == cloudformation.StackSetStatusDeleted {
+		log.Printf("[WARN] Removing CloudFormation stack set %s (%s) as it's already deleted", d.Id(), aws.StringValue(stackSet.StackSetId))
+		d.SetId("")
+		return nil
+	}
+
+	instances, err := listCloudFormationStackSetInstances(conn, d.Id())
+	if err != nil {
+		return fmt.Errorf("Listing CloudFormation stack set %q instances failed: %s", d.Id(), err)
+	}
+
+	// The template and parameters of a stack set which was read before are
+	// mid-change while an operation is running, so they are kept as they were
+	// rather than recording a transient state. A running operation leaves the
+	// instances it hasn't reached yet OUTDATED, so the operations are only
+	// listed then.
+	inProgress := false
+	if d.Get("stack_set_id").(string) != "" && cloudFormationStackSetOutdatedInstanceCount(instances) > 0 {
+		runningOperationId, err := findCloudFormationStackSetRunningOperationId(conn, d.Id())
+		if err != nil {
+			return err
+		}
+		inProgress = runningOperationId != ""
+		if inProgress {
+			log.Printf("[WARN] CloudFormation stack set %q operation %q is in progress, not refreshing its template and parameters", d.Id(), runningOperationId)
+		}
+	}
+
+	d.Set("name", stackSet.StackSetName)
+	d.Set("arn", cloudFormationStackSetArn(meta.(*AWSClient), aws.StringValue(stackSet.StackSetId)))
+	d.Set("stack_set_id", stackSet.StackSetId)
+	d.Set("description", stackSet.Description)
+	d.Set("administration_role_name", cloudFormationStackSetAdministrationRoleName)
+	d.Set("execution_role_name", cloudFormationStackSetExecutionRoleName)
+
+	if stackSet.TemplateBody != nil && !inProgress {
+		template, err := normalizeCloudFormationTemplate(*stackSet.TemplateBody)
+		if err != nil {
+			return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+		}
+		d.Set("template_body", template)
+		d.Set("template_body_hash", cloudFormationTemplateHash(template))
+
+		d.Set("detected_template_format", cloudFormationTemplateFormat(template))
+
+		transforms, err := flattenCloudFormationTemplateTransforms(template)
+		if err != nil {
+			return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+		}
+		if err := d.Set("transforms", transforms); err != nil {
+			return err
+		}
+	}
+
+	if !inProgress {
+		originalParams := d.Get("parameters").(map[string]interface{})
+		err = d.Set("parameters", flattenCloudFormationParameters(stackSet.Parameters, originalParams))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.Set("tags", flattenCloudFormationTags(stackSet.Tags))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("stack_ids", cloudFormationStackSetInstanceStackIds(instances))
+	if err != nil {
+		return err
+	}
+	err = d.Set("deployed_regions", cloudFormationStackSetInstanceRegions(instances))
+	if err != nil {
+		return err
+	}
+	d.Set("outdated_instance_count", cloudFormationStackSetOutdatedInstanceCount(instances))
+
+	err = d.Set("effective_instance_tags", getCloudFormationStackSetInstanceTags(conn, meta.(*AWSClient), instances))
+	if err != nil {
+		return err
+	}
+
+	// Capabilities added by AWS on its own are only reported as effective
+	// capabilities so they don't show up as a difference to the configuration
+	configuredCapabilities := d.Get("capabilities").(*schema.Set)
+	err = d.Set("capabilities", cloudFormationConfiguredCapabilities(configuredCapabilities, stackSet.Capabilities))
+	if err != nil {
+		return err
+	}
+
+	err = d.Set("effective_capabilities", schema.NewSet(schema.HashString, flattenStringList(stackSet.Capabilities)))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func resourceAwsCloudFormationStackSetImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	conn := meta.(*AWSClient).cfconn
+
+	// Either the name or the stack set ID is accepted, but the resource
+	// is identified by the name
+	resp, err := conn.DescribeStackSet(&cloudformation.DescribeStackSetInput{
+		StackSetName: aws.String(d.Id()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A deleted stack set is still described by its ID, and by its name until
+	// another stack set reuses it, but would just be removed again on refresh
+	if aws.StringValue(resp.StackSet.Status) == cloudformation.StackSetStatusDeleted {
+		return nil, fmt.Errorf("CloudFormation stack set %q (%s) is already deleted, import an active stack set instead",
+			aws.StringValue(resp.StackSet.StackSetName), aws.StringValue(resp.StackSet.StackSetId))
+	}
+
+	// Refreshing describes the stack set by its ID, which never returns a
+	// deleted stack set of the same name
+	d.SetId(aws.StringValue(resp.StackSet.StackSetName))
+	d.Set("name", resp.StackSet.StackSetName)
+	d.Set("stack_set_id", resp.StackSet.StackSetId)
+
+	// Nothing is configured yet, so all capabilities and parameters in
+	// effect are imported
+	d.Set("capabilities", schema.NewSet(schema.HashString, flattenStringList(resp.StackSet.Capabilities)))
+	if err := d.Set("parameters", flattenAllCloudFormationParameters(resp.StackSet.Parameters)); err != nil {
+		return nil, err
+	}
+
+	// Arguments only used by Terraform aren't known to AWS, operation
+	// preferences included, so they take their defaults
+	for _, k := range []string{"prevent_update", "warn_unnecessary_capabilities", "warn_missing_parameters", "stop_operation_on_timeout",
+		"retain_stacks_on_delete", "check_running_operations", "treat_partial_failure_as_error"} {
+		d.Set(k, false)
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceAwsCloudFormationStackSetUpdate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	if d.Get("prevent_update").(bool) {
+		log.Printf("[WARN] Not updating CloudFormation stack set %q as prevent_update is enabled", d.Id())
+		return resourceAwsCloudFormationStackSetRead(d, meta)
+	}
+
+	// Arguments only used by Terraform itself don't require a stack set operation
+	if !d.HasChange("description") && !d.HasChange("template_body") && !d.HasChange("template_url") &&
+		!d.HasChange("capabilities") && !d.HasChange("parameters") && !d.HasChange("tags") {
+		return resourceAwsCloudFormationStackSetRead(d, meta)
+	}
+
+	// The operation ID makes retried requests idempotent
+	operationId := resource.UniqueId()
+	input := &cloudformation.UpdateStackSetInput{
+		StackSetName: aws.String(d.Id()),
+		OperationId:  aws.String(operationId),
+	}
+
+	// CloudFormation keeps the description when it's omitted and rejects an
+	// empty one, so it can be changed but not removed
+	description := d.Get("description").(string)
+	if d.HasChange("description") && description == "" {
+		return fmt.Errorf("CloudFormation stack set %q description can't be removed, only changed", d.Id())
+	}
+	if description != "" {
+		input.Description = aws.String(description)
+	}
+
+	// Either TemplateBody, TemplateURL or UsePreviousTemplate are required.
+	// Only re-send the template when it changed, e.g. changing capabilities
+	// alone is applied to the template already in use.
+	if d.HasChange("template_body") || d.HasChange("template_url") {
+		if v, ok := d.GetOk("template_url"); ok {
+			input.TemplateURL = aws.String(v.(string))
+		}
+		if v, ok := d.GetOk("template_body"); ok && input.TemplateURL == nil {
+			template, err := normalizeCloudFormationTemplate(v)
+			if err != nil {
+				return errwrap.Wrapf("template body contains an invalid JSON or YAML: {{err}}", err)
+			}
+			input.TemplateBody = aws.String(template)
+		}
+	} else {
+		input.UsePreviousTemplate = aws.Bool(true)
+	}
+
+	// Capabilities must be present whether they are changed or not,
+	// sending an empty list removes all of them
+	input.Capabilities = expandStringList(d.Get("capabilities").(*schema.Set).List())
+
+	// Parameters must be present whether they are changed or not
+	if v, ok := d.GetOk("parameters"); ok {
+		input.Parameters = expandCloudFormationParameters(v.(map[string]interface{}))
+	}
+
+	// Omitted tags are kept as they are, sending an empty list removes all of them
+	input.Tags = append([]*cloudformation.Tag{}, expandCloudFormationTags(d.Get("tags").(map[string]interface{}))...)
+
+	input.OperationPreferences = expandCloudFormationStackSetOperationPreferences(d.Get("operation_preferences").([]interface{}))
+
+	// All waits share the update timeout, each getting what is left of it
+	start := time.Now()
+	timeout := d.Timeout(schema.TimeoutUpdate)
+
+	// A previous apply may have been interrupted while its operation was
+	// still running. Wait for that operation to finish first, as issuing
+	// a new update in the meantime fails with OperationInProgressException.
+	runningOperationId, err := findCloudFormationStackSetRunningOperationId(conn, d.Id())
+	if err != nil {
+		return err
+	}
+	if runningOperationId != "" {
+		log.Printf("[INFO] Waiting for in-progress CloudFormation stack set %q operation %q", d.Id(), runningOperationId)
+		if err := waitForCloudFormationStackSetOperation(conn, d.Id(), runningOperationId, "update", timeout-time.Since(start)); err != nil {
+			log.Printf("[WARN] In-progress CloudFormation stack set %q operation %q did not succeed: %s", d.Id(), runningOperationId, err)
+		}
+	}
+
+	timeout, err = waitForCloudFormationDeploymentWindow(d, timeout-time.Since(start))
+	if err != nil {
+		return err
+	}
+	start = time.Now()
+
+	log.Printf("[DEBUG] Updating CloudFormation stack set: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.UpdateStackSet(input)
+		return err
+	})
+	if err != nil {
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			err = cloudFormationStackSetOperationError(cloudFormationParameterValueError(err))
+			err = cloudFormationStackSetTemplateUrlError(err, meta.(*AWSClient).region)
+			return fmt.Errorf("Updating CloudFormation stack set %q failed: %s", d.Id(), err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", d.Id(), operationId, err)
+	}
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, d.Id(), operationId, "update", timeout-time.Since(start),
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	// The operation succeeds as long as its failed stack instances stay
+	// within the failure tolerance
+	failures, err := getCloudFormationStackSetOperationFailures(conn, d.Id(), operationId)
+	if err != nil {
+		return fmt.Errorf("Failed getting failure reasons of CloudFormation stack set operation %q: %s", operationId, err)
+	}
+	if len(failures) > 0 {
+		if d.Get("treat_partial_failure_as_error").(bool) {
+			return fmt.Errorf("CloudFormation stack set %q operation %q succeeded with failed stack instances: %q", d.Id(), operationId, failures)
+		}
+		log.Printf("[WARN] CloudFormation stack set %q operation %q succeeded with failed stack instances: %q", d.Id(), operationId, failures)
+	}
+
+	log.Printf("[DEBUG] CloudFormation stack set %q has been updated", d.Id())
+
+	return resourceAwsCloudFormationStackSetRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackSetDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	// A stack set can only be deleted once all of its instances are gone
+	instances, err := listCloudFormationStackSetInstances(conn, d.Id())
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			return nil
+		}
+		return fmt.Errorf("Listing CloudFormation stack set %q instances failed: %s", d.Id(), err)
+	}
+	if len(instances) > 0 {
+		start := time.Now()
+		timeout := d.Timeout(schema.TimeoutDelete)
+
+		// Like on update, an operation of an interrupted apply has to finish
+		// before the instances can be deleted
+		runningOperationId, err := findCloudFormationStackSetRunningOperationId(conn, d.Id())
+		if err != nil {
+			return err
+		}
+		if runningOperationId != "" {
+			log.Printf("[INFO] Waiting for in-progress CloudFormation stack set %q operation %q", d.Id(), runningOperationId)
+			if err := waitForCloudFormationStackSetOperation(conn, d.Id(), runningOperationId, "delete", timeout-time.Since(start)); err != nil {
+				log.Printf("[WARN] In-progress CloudFormation stack set %q operation %q did not succeed: %s", d.Id(), runningOperationId, err)
+			}
+		}
+
+		retainStacks := d.Get("retain_stacks_on_delete").(bool)
+		preferences := expandCloudFormationStackSetOperationPreferences(d.Get("operation_preferences").([]interface{}))
+		conflictTimeout := cloudFormationStackSetOperationConflictTimeout(d)
+		operationTimeout := cloudFormationStackSetOperationTimeout(d)
+		stopOnTimeout := d.Get("stop_operation_on_timeout").(bool)
+		if err := deleteCloudFormationStackSetInstances(conn, d.Id(), instances, retainStacks, preferences, conflictTimeout,
+			operationTimeout, stopOnTimeout, "delete", timeout-time.Since(start)); err != nil {
+			return err
+		}
+	}
+
+	input := &cloudformation.DeleteStackSetInput{
+		StackSetName: aws.String(d.Id()),
+	}
+	log.Printf("[DEBUG] Deleting CloudFormation stack set: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.DeleteStackSet(input)
+		return err
+	})
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			return nil
+		}
+		return fmt.Errorf("Deleting CloudFormation stack set %q failed: %s", d.Id(), cloudFormationStackSetOperationError(err))
+	}
+
+	log.Printf("[DEBUG] CloudFormation stack set %q has been deleted", d.Id())
+
+	return nil
+}
+
+// deleteCloudFormationStackSetInstances deletes the given instances of the
+// stack set within the timeout. DeleteStackInstances acts on every region of
+// each account, so accounts are grouped by their regions and each group is
+// deleted by its own operation, which may run up to the operation timeout.
+func deleteCloudFormationStackSetInstances(conn *cloudformation.CloudFormation, stackSetName string, instances []*cloudformation.StackInstanceSummary,
+	retainStacks bool, preferences *cloudformation.StackSetOperationPreferences, conflictTimeout, operationTimeout time.Duration, stopOnTimeout bool,
+	phase string, timeout time.Duration) error {
+	start := time.Now()
+	for _, target := range cloudFormationStackSetInstanceTargets(instances) {
+		operationId := resource.UniqueId()
+		input := &cloudformation.DeleteStackInstancesInput{
+			StackSetName:         aws.String(stackSetName),
+			Accounts:             aws.StringSlice(target.Accounts),
+			Regions:              aws.StringSlice(target.Regions),
+			OperationId:          aws.String(operationId),
+			OperationPreferences: preferences,
+			RetainStacks:         aws.Bool(retainStacks),
+		}
+
+		log.Printf("[DEBUG] Deleting CloudFormation stack set instances: %s", input)
+		err := retryCloudFormationStackSetOperation(conn, conflictTimeout, func() error {
+			_, err := conn.DeleteStackInstances(input)
+			return err
+		})
+		if err != nil {
+			if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+				return fmt.Errorf("Deleting CloudFormation stack set %q instances failed: %s", stackSetName, cloudFormationStackSetOperationError(err))
+			}
+			log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+		}
+
+		if err := waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, phase, timeout-time.Since(start), operationTimeout, stopOnTimeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type cloudFormationStackSetInstanceTarget struct {
+	Accounts []string
+	Regions  []string
+}
+
+// cloudFormationStackSetInstanceTargets groups the accounts of the instances
+// by their sorted regions, so that no instance outside of them is targeted
+func cloudFormationStackSetInstanceTargets(instances []*cloudformation.StackInstanceSummary) []cloudFormationStackSetInstanceTarget {
+	regions := make(map[string][]string)
+	for _, instance := range instances {
+		account := aws.StringValue(instance.Account)
+		regions[account] = append(regions[account], aws.StringValue(instance.Region))
+	}
+
+	var keys []string
+	targets := make(map[string]*cloudFormationStackSetInstanceTarget)
+	for account, accountRegions := range regions {
+		sort.Strings(accountRegions)
+		key := strings.Join(accountRegions, ",")
+		if _, ok := targets[key]; !ok {
+			keys = append(keys, key)
+			targets[key] = &cloudFormationStackSetInstanceTarget{Regions: accountRegions}
+		}
+		targets[key].Accounts = append(targets[key].Accounts, account)
+	}
+
+	sort.Strings(keys)
+	result := make([]cloudFormationStackSetInstanceTarget, 0, len(keys))
+	for _, key := range keys {
+		sort.Strings(targets[key].Accounts)
+		result = append(result, *targets[key])
+	}
+	return result
+}
+
+func resourceAwsCloudFormationStackSetCustomizeDiff(diff *schema.ResourceDiff, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	if err := validateCloudFormationStackSetOperationPreferences(diff.Get("operation_preferences").([]interface{})); err != nil {
+		return err
+	}
+
+	templateChanged := diff.HasChange("template_body") || diff.HasChange("template_url")
+	if templateChanged {
+		if err := setCloudFormationStackSetDiffTemplateAttributes(diff); err != nil {
+			return err
+		}
+	}
+
+	// Terraform has no way to surface warnings from a plan, so they're logged.
+	// This is opt-in as it costs an extra API call whenever the template changes.
+	if diff.Id() != "" && templateChanged && diff.Get("check_running_operations").(bool) {
+		runningOperationId, err := findCloudFormationStackSetRunningOperationId(conn, diff.Id())
+		if err != nil {
+			log.Printf("[WARN] Unable to check CloudFormation stack set %q for running operations: %s", diff.Id(), err)
+		} else if runningOperationId != "" {
+			log.Printf("[WARN] CloudFormation stack set %q operation %q is still in progress. "+
+				"Applying the template change has to wait for it and may fail with OperationInProgressException "+
+				"if another operation starts in the meantime, consider waiting for it to finish.", diff.Id(), runningOperationId)
+		}
+	}
+
+	if diff.Get("warn_unnecessary_capabilities").(bool) && (templateChanged || diff.HasChange("capabilities")) {
+		if v, ok := diff.GetOk("template_body"); ok {
+			capabilities := aws.StringValueSlice(expandStringList(diff.Get("capabilities").(*schema.Set).List()))
+			unnecessary, err := cloudFormationUnnecessaryCapabilities(v.(string), capabilities)
+			if err != nil {
+				log.Printf("[WARN] Unable to check CloudFormation stack set %q template for unnecessary capabilities: %s", diff.Get("name").(string), err)
+			} else if len(unnecessary) > 0 {
+				log.Printf("[WARN] CloudFormation stack set %q template declares no IAM resources, "+
+					"the capabilities %s are unnecessary", diff.Get("name").(string), strings.Join(unnecessary, ", "))
+			}
+		}
+	}
+
+	// Parameters interpolated from other resources aren't known until apply
+	if !diff.NewValueKnown("parameters") {
+		log.Printf("[DEBUG] CloudFormation stack set %q parameters aren't known yet, skipping parameter validation", diff.Get("name").(string))
+		return nil
+	}
+	if !templateChanged && !diff.HasChange("parameters") {
+		return nil
+	}
+	parameters := diff.Get("parameters").(map[string]interface{})
+
+	templateBody, hasTemplateBody := diff.GetOk("template_body")
+	if hasTemplateBody {
+		violations, err := cloudFormationParameterConstraintViolations(templateBody.(string), parameters)
+		if err != nil {
+			// Leave reporting issues with the template itself to the apply
+			log.Printf("[WARN] Unable to decode CloudFormation stack set template, skipping parameter constraint validation: %s", err)
+		} else if len(violations) > 0 {
+			return fmt.Errorf("CloudFormation stack set %q parameters violate the constraints declared by the template:\n\n%s",
+				diff.Get("name").(string), strings.Join(violations, "\n"))
+		}
+	}
+
+	// This is opt-in as it costs an extra API call whenever the template or parameters change
+	if !diff.Get("warn_missing_parameters").(bool) {
+		return nil
+	}
+
+	input := &cloudformation.GetTemplateSummaryInput{}
+	if v, ok := diff.GetOk("template_url"); ok {
+		input.TemplateURL = aws.String(v.(string))
+	} else if hasTemplateBody {
+		input.TemplateBody = aws.String(templateBody.(string))
+	} else {
+		// The template isn't known until apply
+		return nil
+	}
+
+	summary, err := conn.GetTemplateSummary(input)
+	if err != nil {
+		// Leave reporting issues with the template itself to the apply
+		log.Printf("[WARN] Unable to get CloudFormation stack set template summary, skipping parameter validation: %s", err)
+		return nil
+	}
+
+	if missing := cloudFormationMissingRequiredParameters(summary.Parameters, parameters); len(missing) > 0 {
+		log.Printf("[WARN] CloudFormation stack set %q template requires parameters without a default value, "+
+			"which are not provided: %s", diff.Get("name").(string), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// setCloudFormationStackSetDiffTemplateAttributes plans the attributes Read
+// derives from the template, so that they don't show the previous template
+// until the next refresh. They are only known after apply for a template_url
+// or a template_body interpolated from other resources.
+func setCloudFormationStackSetDiffTemplateAttributes(diff *schema.ResourceDiff) error {
+	template, ok := "", false
+	if _, hasUrl := diff.GetOk("template_url"); !hasUrl && diff.NewValueKnown("template_body") {
+		var err error
+		template, err = normalizeCloudFormationTemplate(diff.Get("template_body").(string))
+		ok = err == nil && template != ""
+	}
+	var transforms []string
+	if ok {
+		var err error
+		transforms, err = flattenCloudFormationTemplateTransforms(template)
+		ok = err == nil
+	}
+
+	if !ok {
+		// Leave reporting issues with the template itself to the apply
+		for _, k := range []string{"template_body_hash", "detected_template_format", "transforms"} {
+			if err := diff.SetNewComputed(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := diff.SetNew("template_body_hash", cloudFormationTemplateHash(template)); err != nil {
+		return err
+	}
+	if err := diff.SetNew("detected_template_format", cloudFormationTemplateFormat(template)); err != nil {
+		return err
+	}
+	return diff.SetNew("transforms", transforms)
+}
+
+// validateCloudFormationStackSetOperationPreferences enforces that either the
+// count or the percentage of the failure tolerance and concurrency is set
+func validateCloudFormationStackSetOperationPreferences(configured []interface{}) error {
+	if len(configured) == 0 || configured[0] == nil {
+		return nil
+	}
+
+	m := configured[0].(map[string]interface{})
+	for _, name := range []string{"failure_tolerance", "max_concurrent"} {
+		if m[name+"_count"].(int) > 0 && m[name+"_percentage"].(int) > 0 {
+			return fmt.Errorf("operation_preferences: only one of %s_count or %s_percentage can be set", name, name)
+		}
+	}
+	return nil
+}
+
+// cloudFormationUnnecessaryCapabilities returns the sorted IAM capabilities
+// acknowledged for a template without any IAM resources
+func cloudFormationUnnecessaryCapabilities(templateBody string, capabilities []string) ([]string, error) {
+	template, err := decodeCloudFormationTemplate(templateBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON decodes to string keys, YAML to interface{} keys
+	var resources []interface{}
+	switch r := template["Resources"].(type) {
+	case map[string]interface{}:
+		for _, v := range r {
+			resources = append(resources, v)
+		}
+	case map[interface{}]interface{}:
+		for _, v := range r {
+			resources = append(resources, v)
+		}
+	}
+	for _, definition := range resources {
+		var resourceType interface{}
+		switch r := definition.(type) {
+		case map[string]interface{}:
+			resourceType = r["Type"]
+		case map[interface{}]interface{}:
+			resourceType = r["Type"]
+		}
+		if t, ok := resourceType.(string); ok && strings.HasPrefix(t, "AWS::IAM::") {
+			return nil, nil
+		}
+	}
+
+	var unnecessary []string
+	for _, c := range capabilities {
+		if c == cloudformation.CapabilityCapabilityIam || c == cloudformation.CapabilityCapabilityNamedIam {
+			unnecessary = append(unnecessary, c)
+		}
+	}
+	sort.Strings(unnecessary)
+	return unnecessary, nil
+}
+
+// cloudFormationParameterConstraintViolations checks the parameter values
+// against the AllowedValues, AllowedPattern, MinLength and MaxLength
+// constraints declared by the template, describing each violation
+func cloudFormationParameterConstraintViolations(templateBody string, parameters map[string]interface{}) ([]string, error) {
+	template, err := decodeCloudFormationTemplate(templateBody)
+	if err != nil {
+		return nil, err
+	}
+	declarations := cloudFormationTemplateSection(template["Parameters"])
+
+	var violations []string
+	for key, raw := range parameters {
+		value, ok := raw.(string)
+		if !ok || value == config.UnknownVariableValue {
+			continue
+		}
+		declaration := cloudFormationTemplateSection(declarations[key])
+		if declaration == nil {
+			continue
+		}
+		// Constraints of lists apply to their elements
+		if t, _ := declaration["Type"].(string); t == "CommaDelimitedList" || strings.HasPrefix(t, "List<") {
+			continue
+		}
+
+		if allowed, ok := declaration["AllowedValues"].([]interface{}); ok {
+			found := false
+			values := make([]string, 0, len(allowed))
+			for _, v := range allowed {
+				values = append(values, fmt.Sprintf("%v", v))
+				found = found || values[len(values)-1] == value
+			}
+			if !found {
+				violations = append(violations, fmt.Sprintf("%s: %q is not one of the AllowedValues %q", key, value, values))
+			}
+		}
+		if pattern, ok := declaration["AllowedPattern"].(string); ok {
+			// The pattern has to match the whole value
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				log.Printf("[WARN] Unable to compile AllowedPattern %q of CloudFormation parameter %s: %s", pattern, key, err)
+			} else if !re.MatchString(value) {
+				violations = append(violations, fmt.Sprintf("%s: %q does not match the AllowedPattern %q", key, value, pattern))
+			}
+		}
+		length := len([]rune(value))
+		if min, ok := cloudFormationTemplateInt(declaration["MinLength"]); ok && length < min {
+			violations = append(violations, fmt.Sprintf("%s: %q is shorter than the MinLength of %d", key, value, min))
+		}
+		if max, ok := cloudFormationTemplateInt(declaration["MaxLength"]); ok && length > max {
+			violations = append(violations, fmt.Sprintf("%s: %q is longer than the MaxLength of %d", key, value, max))
+		}
+	}
+
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// cloudFormationTemplateSection returns a decoded template section as a map,
+// whether it was decoded from JSON or YAML
+func cloudFormationTemplateSection(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		section := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if key, ok := k.(string); ok {
+				section[key] = v
+			}
+		}
+		return section
+	}
+	return nil
+}
+
+// cloudFormationTemplateInt returns a number of a decoded template, which
+// may also be written as a string
+func cloudFormationTemplateInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// cloudFormationMissingRequiredParameters returns the sorted keys of the
+// declared template parameters which have neither a default nor a value
+func cloudFormationMissingRequiredParameters(declarations []*cloudformation.ParameterDeclaration, parameters map[string]interface{}) []string {
+	var missing []string
+	for _, p := range declarations {
+		if p.DefaultValue != nil {
+			continue
+		}
+		if _, ok := parameters[aws.StringValue(p.ParameterKey)]; !ok {
+			missing = append(missing, aws.StringValue(p.ParameterKey))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// findCloudFormationStackSetActiveId pages through the active stack sets
+// and returns the ID of the one with the given name
+func findCloudFormationStackSetActiveId(conn *cloudformation.CloudFormation, stackSetName string) (string, error) {
+	input := &cloudformation.ListStackSetsInput{
+		Status: aws.String(cloudformation.StackSetStatusActive),
+	}
+	for {
+		resp, err := conn.ListStackSets(input)
+		if err != nil {
+			return "", fmt.Errorf("Error listing CloudFormation stack sets: %s", err)
+		}
+
+		for _, summary := range resp.Summaries {
+			if aws.StringValue(summary.StackSetName) == stackSetName {
+				return aws.StringValue(summary.StackSetId), nil
+			}
+		}
+
+		if resp.NextToken == nil {
+			return "", fmt.Errorf("CloudFormation stack set %q not found after creating it", stackSetName)
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// listCloudFormationStackSetInstances pages through all instances of a stack set
+func listCloudFormationStackSetInstances(conn *cloudformation.CloudFormation, stackSetName string) ([]*cloudformation.StackInstanceSummary, error) {
+	return filterCloudFormationStackSetInstances(conn, &cloudformation.ListStackInstancesInput{
+		StackSetName: aws.String(stackSetName),
+	})
+}
+
+// filterCloudFormationStackSetInstances pages through the instances of the
+// stack set matching the account and region filters of the input
+func filterCloudFormationStackSetInstances(conn *cloudformation.CloudFormation, input *cloudformation.ListStackInstancesInput) ([]*cloudformation.StackInstanceSummary, error) {
+	var instances []*cloudformation.StackInstanceSummary
+
+	for {
+		resp, err := conn.ListStackInstances(input)
+		if err != nil {
+			return nil, err
+		}
+
+		instances = append(instances, resp.Summaries...)
+
+		if resp.NextToken == nil {
+			return instances, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// cloudFormationStackSetInstanceStackIds returns the sorted IDs of the stacks
+// deployed by the instances, skipping those without a stack yet
+func cloudFormationStackSetInstanceStackIds(instances []*cloudformation.StackInstanceSummary) []string {
+	stackIds := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		if instance.StackId != nil {
+			stackIds = append(stackIds, *instance.StackId)
+		}
+	}
+	sort.Strings(stackIds)
+	return stackIds
+}
+
+// cloudFormationStackSetInstanceRegions returns the distinct regions of the
+// instances, including those without a stack yet
+func cloudFormationStackSetInstanceRegions(instances []*cloudformation.StackInstanceSummary) []string {
+	seen := make(map[string]bool)
+	regions := make([]string, 0)
+	for _, instance := range instances {
+		region := aws.StringValue(instance.Region)
+		if region != "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// cloudFormationStackSetOutdatedInstanceCount returns how many instances the
+// latest update of the stack set has not reached yet
+func cloudFormationStackSetOutdatedInstanceCount(instances []*cloudformation.StackInstanceSummary) int {
+	count := 0
+	for _, instance := range instances {
+		if aws.StringValue(instance.Status) == cloudformation.StackInstanceStatusOutdated {
+			count++
+		}
+	}
+	return count
+}
+
+// getCloudFormationStackSetInstanceTags returns the tags of a stack deployed
+// by one of the instances, as a sample of the tags propagated from the stack
+// set. Only stacks in the account and region of the provider can be read.
+func getCloudFormationStackSetInstanceTags(conn *cloudformation.CloudFormation, client *AWSClient, instances []*cloudformation.StackInstanceSummary) map[string]string {
+	for _, instance := range instances {
+		if instance.StackId == nil || aws.StringValue(instance.Account) != client.accountid || aws.StringValue(instance.Region) != client.region {
+			continue
+		}
+
+		resp, err := conn.DescribeStacks(&cloudformation.DescribeStacksInput{
+			StackName: instance.StackId,
+		})
+		if err != nil {
+			log.Printf("[WARN] Unable to read tags of CloudFormation stack set instance stack %q: %s", *instance.StackId, err)
+			continue
+		}
+		if len(resp.Stacks) == 0 {
+			continue
+		}
+
+		return flattenCloudFormationTags(resp.Stacks[0].Tags)
+	}
+
+	return map[string]string{}
+}
+
+// waitForCloudFormationDeploymentWindow waits for the deployment_window to
+// open and returns what is left of the timeout afterwards
+func waitForCloudFormationDeploymentWindow(d *schema.ResourceData, timeout time.Duration) (time.Duration, error) {
+	v, ok := d.GetOk("deployment_window")
+	if !ok {
+		return timeout, nil
+	}
+	window := v.(string)
+
+	delay, err := cloudFormationDeploymentWindowDelay(window, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if delay >= timeout {
+		return 0, fmt.Errorf("deployment_window %q opens in %s, which exceeds the timeout of %s", window, delay, timeout)
+	}
+	if delay > 0 {
+		log.Printf("[INFO] Waiting %s for deployment_window %q to open", delay, window)
+		time.Sleep(delay)
+	}
+
+	return timeout - delay, nil
+}
+
+// cloudFormationDeploymentWindowDelay returns how long it takes from now
+// until the daily window in UTC, given as "hh24:mi-hh24:mi", is open
+func cloudFormationDeploymentWindowDelay(window string, now time.Time) (time.Duration, error) {
+	parts := strings.Split(window, "-")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("deployment_window %q must satisfy the format of \"hh24:mi-hh24:mi\"", window)
+	}
+	start, err := time.Parse("15:04", parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("deployment_window %q has an invalid start: %s", window, err)
+	}
+	end, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("deployment_window %q has an invalid end: %s", window, err)
+	}
+	if start.Equal(end) {
+		return 0, fmt.Errorf("deployment_window %q must not start and end at the same time", window)
+	}
+
+	now = now.UTC()
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	startOffset := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	endOffset := time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+
+	var open bool
+	if startOffset < endOffset {
+		open = sinceMidnight >= startOffset && sinceMidnight < endOffset
+	} else {
+		// The window spans midnight
+		open = sinceMidnight >= startOffset || sinceMidnight < endOffset
+	}
+	if open {
+		return 0, nil
+	}
+
+	delay := startOffset - sinceMidnight
+	if delay < 0 {
+		delay += 24 * time.Hour
+	}
+	return delay, nil
+}
+
+// cloudFormationTemplateHash returns the SHA-256 of a normalized template
+func cloudFormationTemplateHash(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return hex.EncodeToString(hash[:])
+}
+
+// cloudFormationConfiguredCapabilities returns the reported capabilities
+// which are part of the configured ones
+func cloudFormationConfiguredCapabilities(configured *schema.Set, reported []*string) *schema.Set {
+	capabilities := schema.NewSet(schema.HashString, nil)
+	for _, c := range reported {
+		if configured.Contains(aws.StringValue(c)) {
+			capabilities.Add(aws.StringValue(c))
+		}
+	}
+	return capabilities
+}
+
+// cloudFormationStackSetArn builds the ARN of a stack set in the partition
+// of the provider, as DescribeStackSet doesn't return it
+func cloudFormationStackSetArn(client *AWSClient, stackSetId string) string {
+	return arnString(
+		client.partition,
+		client.region,
+		cloudformation.ServiceName,
+		client.accountid,
+		fmt.Sprintf("stackset/%s", stackSetId))
+}
+
+// waitForCloudFormationStackSetOperation blocks until the given stack set
+// operation reaches a terminal status and returns an error unless it succeeded.
+// The phase, e.g. "update", names the lifecycle step waiting on timeouts.
+func waitForCloudFormationStackSetOperation(conn *cloudformation.CloudFormation, stackSetName, operationId, phase string, timeout time.Duration) error {
+	wait := resource.StateChangeConf{
+		Pending: []string{
+			cloudformation.StackSetOperationStatusRunning,
+			cloudformation.StackSetOperationStatusStopping,
+		},
+		Target: []string{
+			cloudformation.StackSetOperationStatusSucceeded,
+			cloudformation.StackSetOperationStatusFailed,
+			cloudformation.StackSetOperationStatusStopped,
+		},
+		Timeout:    timeout,
+		MinTimeout: 5 * time.Second,
+		// A just started operation may not be found for a few checks
+		NotFoundChecks: 5,
+		Refresh:        cloudFormationStackSetOperationRefreshFunc(conn, stackSetName, operationId),
+	}
+
+	operation, err := wait.WaitForState()
+	if err != nil {
+		if timeoutErr, ok := err.(*resource.TimeoutError); ok {
+			lastStatus := timeoutErr.LastState
+			if lastStatus == "" {
+				lastStatus = "unknown"
+			}
+			return errwrap.Wrap(fmt.Errorf("Timeout during %s of CloudFormation stack set %q: operation %q did not finish within %s, last observed status: %s",
+				phase, stackSetName, operationId, timeout, lastStatus), timeoutErr)
+		}
+		return err
+	}
+
+	status := aws.StringValue(operation.(*cloudformation.StackSetOperation).Status)
+	if status == cloudformation.StackSetOperationStatusSucceeded {
+		return nil
+	}
+
+	reasons, err := getCloudFormationStackSetOperationFailures(conn, stackSetName, operationId)
+	if err != nil {
+		return fmt.Errorf("Failed getting failure reasons of CloudFormation stack set operation %q: %s", operationId, err)
+	}
+
+	return fmt.Errorf("CloudFormation stack set %q operation %q %s: %q", stackSetName, operationId, status, reasons)
+}
+
+// retryCloudFormationStackSetOperation calls f until it no longer fails
+// because of another operation of the stack set. It gives up once the
+// conflict timeout elapsed, if set, or otherwise after the provider's
+// max_retries like the SDK does for throttled requests.
+func retryCloudFormationStackSetOperation(conn *cloudformation.CloudFormation, conflictTimeout time.Duration, f func() error) error {
+	if conflictTimeout > 0 {
+		return resource.Retry(conflictTimeout, func() *resource.RetryError {
+			err := f()
+			if isCloudFormationStackSetOperationConflict(err) {
+				log.Printf("[DEBUG] Retrying CloudFormation stack set operation for up to %s: %s", conflictTimeout, err)
+				return resource.RetryableError(err)
+			}
+			if err != nil {
+				return resource.NonRetryableError(err)
+			}
+			return nil
+		})
+	}
+
+	maxRetries := conn.MaxRetries()
+	for retry := 0; ; retry++ {
+		err := f()
+		if err == nil || retry >= maxRetries || !isCloudFormationStackSetOperationConflict(err) {
+			return err
+		}
+
+		delay := time.Duration(1<<uint(retry)) * time.Second
+		if delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+		log.Printf("[DEBUG] Retrying CloudFormation stack set operation in %s (%d/%d): %s", delay, retry+1, maxRetries, err)
+		time.Sleep(delay)
+	}
+}
+
+// isCloudFormationStackSetOperationConflict returns true when a request was
+// rejected because another operation of the stack set is in progress
+func isCloudFormationStackSetOperationConflict(err error) bool {
+	return isAWSErr(err, cloudformation.ErrCodeOperationInProgressException, "") ||
+		isAWSErr(err, cloudformation.ErrCodeStaleRequestException, "")
+}
+
+// cloudFormationStackSetOperationConflictTimeout returns how long requests
+// conflicting with another operation are retried, zero if not configured
+func cloudFormationStackSetOperationConflictTimeout(d *schema.ResourceData) time.Duration {
+	return time.Duration(d.Get("operation_conflict_timeout_in_minutes").(int)) * time.Minute
+}
+
+// cloudFormationStackSetOperationTimeout returns how long a stack set
+// operation may run, zero if only the resource timeout applies
+func cloudFormationStackSetOperationTimeout(d *schema.ResourceData) time.Duration {
+	return time.Duration(d.Get("operation_timeout_in_minutes").(int)) * time.Minute
+}
+
+// waitForCloudFormationStackSetOperationWithin waits for the operation like
+// waitForCloudFormationStackSetOperation, but fails once it ran longer than
+// the operation timeout, if set and shorter, and optionally stops it
+func waitForCloudFormationStackSetOperationWithin(conn *cloudformation.CloudFormation, stackSetName, operationId, phase string, timeout, operationTimeout time.Duration, stop bool) error {
+	if operationTimeout <= 0 || operationTimeout >= timeout {
+		return waitForCloudFormationStackSetOperation(conn, stackSetName, operationId, phase, timeout)
+	}
+
+	err := waitForCloudFormationStackSetOperation(conn, stackSetName, operationId, phase, operationTimeout)
+	if err == nil || !stop || !errwrap.ContainsType(err, &resource.TimeoutError{}) {
+		return err
+	}
+
+	log.Printf("[WARN] Stopping CloudFormation stack set %q operation %q, which exceeded its timeout of %s", stackSetName, operationId, operationTimeout)
+	_, stopErr := conn.StopStackSetOperation(&cloudformation.StopStackSetOperationInput{
+		StackSetName: aws.String(stackSetName),
+		OperationId:  aws.String(operationId),
+	})
+	if stopErr != nil {
+		return fmt.Errorf("%s\n\nStopping the operation failed: %s", err, stopErr)
+	}
+	return fmt.Errorf("%s\n\nThe operation is stopping, as requested by stop_operation_on_timeout.", err)
+}
+
+func cloudFormationStackSetOperationRefreshFunc(conn *cloudformation.CloudFormation, stackSetName, operationId string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.DescribeStackSetOperation(&cloudformation.DescribeStackSetOperationInput{
+			StackSetName: aws.String(stackSetName),
+			OperationId:  aws.String(operationId),
+		})
+		if err != nil {
+			if isAWSErr(err, cloudformation.ErrCodeOperationNotFoundException, "") {
+				log.Printf("[DEBUG] CloudFormation stack set %q operation %q not found yet", stackSetName, operationId)
+				return nil, "", nil
+			}
+			log.Printf("[ERROR] Failed to describe stack set operation: %s", err)
+			return nil, "", err
+		}
+
+		status := aws.StringValue(resp.StackSetOperation.Status)
+		log.Printf("[DEBUG] Current CloudFormation stack set %q operation %q status: %q", stackSetName, operationId, status)
+
+		return resp.StackSetOperation, status, nil
+	}
+}
+
+// getCloudFormationStackSetOperationFailures pages through the results of
+// a stack set operation and returns the reasons of the failed stack instances
+func getCloudFormationStackSetOperationFailures(conn *cloudformation.CloudFormation, stackSetName, operationId string) ([]string, error) {
+	var failures []string
+
+	input := &cloudformation.ListStackSetOperationResultsInput{
+		StackSetName: aws.String(stackSetName),
+		OperationId:  aws.String(operationId),
+	}
+	for {
+		resp, err := conn.ListStackSetOperationResults(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range resp.Summaries {
+			if aws.StringValue(r.Status) == cloudformation.StackSetOperationResultStatusFailed {
+				failures = append(failures, cloudFormationStackSetOperationResultFailure(r))
+			}
+		}
+
+		if resp.NextToken == nil {
+			return failures, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// cloudFormationStackSetOperationResultFailure describes why a single stack instance
+// failed. The administration role being unable to assume the execution role
+// in the target account is common enough to be called out explicitly.
+func cloudFormationStackSetOperationResultFailure(r *cloudformation.StackSetOperationResultSummary) string {
+	account := aws.StringValue(r.Account)
+	region := aws.StringValue(r.Region)
+	reason := aws.StringValue(r.StatusReason)
+
+	lower := strings.ToLower(reason)
+	if strings.Contains(lower, "assume") || strings.Contains(lower, "trust relationship") {
+		return fmt.Sprintf("account %s (%s): the administration role can't assume the execution role in account %s, "+
+			"check that the execution role exists there and trusts the administration role: %s", account, region, account, reason)
+	}
+
+	return fmt.Sprintf("account %s (%s): %s", account, region, reason)
+}
+
+// findCloudFormationStackSetRunningOperationId returns the ID of the operation
+// of a stack set still in progress, if any. The order operations are listed in
+// isn't documented, so like findCloudFormationStackSetLastOperation this pages
+// through all of them.
+func findCloudFormationStackSetRunningOperationId(conn *cloudformation.CloudFormation, stackSetName string) (string, error) {
+	var summaries []*cloudformation.StackSetOperationSummary
+
+	input := &cloudformation.ListStackSetOperationsInput{
+		StackSetName: aws.String(stackSetName),
+	}
+	for {
+		resp, err := listCloudFormationStackSetOperations(conn, input)
+		if err != nil {
+			return "", fmt.Errorf("Error listing CloudFormation stack set %q operations: %s", stackSetName, err)
+		}
+		summaries = append(summaries, resp.Summaries...)
+
+		if resp.NextToken == nil {
+			return cloudFormationStackSetRunningOperationId(summaries), nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// findCloudFormationStackSetLastOperation returns the most recently created
+// operation of a stack set, or nil if it never had one
+func findCloudFormationStackSetLastOperation(conn *cloudformation.CloudFormation, stackSetName string) (*cloudformation.StackSetOperationSummary, error) {
+	var last *cloudformation.StackSetOperationSummary
+
+	input := &cloudformation.ListStackSetOperationsInput{
+		StackSetName: aws.String(stackSetName),
+	}
+	for {
+		resp, err := listCloudFormationStackSetOperations(conn, input)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing CloudFormation stack set %q operations: %s", stackSetName, err)
+		}
+
+		for _, s := range resp.Summaries {
+			if last == nil || aws.TimeValue(s.CreationTimestamp).After(aws.TimeValue(last.CreationTimestamp)) {
+				last = s
+			}
+		}
+
+		if resp.NextToken == nil {
+			return last, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// listCloudFormationStackSetOperations retries a page of operations on
+// throttling, which is likely for stack sets watched by several pipelines
+func listCloudFormationStackSetOperations(conn *cloudformation.CloudFormation, input *cloudformation.ListStackSetOperationsInput) (*cloudformation.ListStackSetOperationsOutput, error) {
+	resp, err := retryOnAwsCodes([]string{"Throttling", "ThrottlingException"}, func() (interface{}, error) {
+		return conn.ListStackSetOperations(input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*cloudformation.ListStackSetOperationsOutput), nil
+}
+
+// cloudFormationStackSetRunningOperationId returns the ID of the most
+// recently created operation which is still running or stopping, if any
+func cloudFormationStackSetRunningOperationId(summaries []*cloudformation.StackSetOperationSummary) string {
+	var running *cloudformation.StackSetOperationSummary
+	for _, s := range summaries {
+		switch aws.StringValue(s.Status) {
+		case cloudformation.StackSetOperationStatusRunning, cloudformation.StackSetOperationStatusStopping:
+			if running == nil || aws.TimeValue(s.CreationTimestamp).After(aws.TimeValue(running.CreationTimestamp)) {
+				running = s
+			}
+		}
+	}
+
+	if running == nil {
+		return ""
+	}
+	return aws.StringValue(running.OperationId)
+}
+
+// cloudFormationStackSetTemplateUrlError explains S3 refusing to serve the
+// template_url, which happens when its bucket is in another region
+func cloudFormationStackSetTemplateUrlError(err error, region string) error {
+	if isAWSErr(err, "ValidationError", "PermanentRedirect") ||
+		isAWSErr(err, "ValidationError", "must be addressed using the specified endpoint") {
+		return fmt.Errorf("%s\n\nThe S3 bucket of template_url must be in the same region as the stack set (%s).", err, region)
+	}
+	return err
+}
+
+// The messages of ValidationErrors for parameter values not matching
+// their declared type, e.g. "Parameter 'Port' must be a number." or
+// "Parameter value sg-1234 for parameter name SecurityGroup does not exist."
+var cloudFormationParameterValueErrorRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`Parameter '([^']+)' must`),
+	regexp.MustCompile(`for parameter name (\S+)`),
+}
+
+// cloudFormationParameterValueError names the parameter whose value
+// CloudFormation rejected, which is buried in the message otherwise
+func cloudFormationParameterValueError(err error) error {
+	if !isAWSErr(err, "ValidationError", "arameter") {
+		return err
+	}
+	for _, re := range cloudFormationParameterValueErrorRegexps {
+		if m := re.FindStringSubmatch(err.Error()); m != nil {
+			return fmt.Errorf("%s\n\nThe value of parameter %q in parameters is not valid for the type declared in the template.", err, m[1])
+		}
+	}
+	return err
+}
+
+// cloudFormationStackSetOperationError adds guidance to errors returned
+// by stack set operations which are otherwise hard to act upon
+func cloudFormationStackSetOperationError(err error) error {
+	if isAWSErr(err, cloudformation.ErrCodeInvalidOperationException, "") {
+		return fmt.Errorf("%s\n\nThe operation is not valid for the permission model of the stack set. "+
+			"Stack sets using the SERVICE_MANAGED permission model are deployed to organizational units "+
+			"by AWS Organizations and can't be targeted at individual accounts.", err)
+	}
+	if isAWSErr(err, cloudformation.ErrCodeStackSetNotEmptyException, "") {
+		return fmt.Errorf("%s\n\nThe stack set still contains stack instances, which have to be deleted "+
+			"before the stack set itself, e.g. by removing its aws_cloudformation_stack_set_instance resources.", err)
+	}
+	return err
+}
@@ -0,0 +1,318 @@
+package aws
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceAwsCloudFormationStackSetInstance() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsCloudFormationStackSetInstanceCreate,
+		Read:   resourceAwsCloudFormationStackSetInstanceRead,
+		Update: resourceAwsCloudFormationStackSetInstanceUpdate,
+		Delete: resourceAwsCloudFormationStackSetInstanceDelete,
+
+		Importer: &schema.ResourceImporter{
+			State: resourceAwsCloudFormationStackSetInstanceImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+			Update: schema.DefaultTimeout(30 * time.Minute),
+			Delete: schema.DefaultTimeout(30 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"stack_set_name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"account_id": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateAwsAccountId,
+			},
+			"region": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"parameter_overrides": {
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"operation_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"operation_conflict_timeout_in_minutes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"stop_operation_on_timeout": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"retain_stack": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"deployment_window": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateOnceADayWindowFormat,
+			},
+			"stack_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"needs_update": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceAwsCloudFormationStackSetInstanceCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName := d.Get("stack_set_name").(string)
+	accountId := d.Get("account_id").(string)
+	region := d.Get("region").(string)
+
+	// The operation ID makes retried requests idempotent
+	operationId := resource.UniqueId()
+	input := &cloudformation.CreateStackInstancesInput{
+		StackSetName: aws.String(stackSetName),
+		Accounts:     []*string{aws.String(accountId)},
+		Regions:      []*string{aws.String(region)},
+		OperationId:  aws.String(operationId),
+	}
+	if v, ok := d.GetOk("parameter_overrides"); ok {
+		input.ParameterOverrides = expandCloudFormationParameters(v.(map[string]interface{}))
+	}
+
+	timeout, err := waitForCloudFormationDeploymentWindow(d, d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Creating CloudFormation stack set instance: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.CreateStackInstances(input)
+		return err
+	})
+	if err != nil {
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			return fmt.Errorf("Creating CloudFormation stack set instance failed: %s", err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+	}
+
+	d.SetId(strings.Join([]string{stackSetName, accountId, region}, ","))
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, "create", timeout,
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] CloudFormation stack set instance %q created", d.Id())
+
+	return resourceAwsCloudFormationStackSetInstanceRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackSetInstanceRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(d.Id())
+	if err != nil {
+		return err
+	}
+
+	input := &cloudformation.DescribeStackInstanceInput{
+		StackSetName:         aws.String(stackSetName),
+		StackInstanceAccount: aws.String(accountId),
+		StackInstanceRegion:  aws.String(region),
+	}
+	resp, err := conn.DescribeStackInstance(input)
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackInstanceNotFoundException, "") ||
+			isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			log.Printf("[WARN] Removing CloudFormation stack set instance %s as it's already gone", d.Id())
+			d.SetId("")
+			return nil
+		}
+
+		return err
+	}
+
+	stackInstance := resp.StackInstance
+	log.Printf("[DEBUG] Received CloudFormation stack set instance: %s", stackInstance)
+
+	d.Set("stack_set_name", stackSetName)
+	d.Set("account_id", stackInstance.Account)
+	d.Set("region", stackInstance.Region)
+	d.Set("stack_id", stackInstance.StackId)
+	// Only overridden parameters are returned, the others take the value of
+	// the stack set
+	if err := d.Set("parameter_overrides", flattenAllCloudFormationParameters(stackInstance.ParameterOverrides)); err != nil {
+		return err
+	}
+	// Instances are OUTDATED until the latest stack set update reached them
+	d.Set("needs_update", aws.StringValue(stackInstance.Status) == cloudformation.StackInstanceStatusOutdated)
+
+	return nil
+}
+
+func resourceAwsCloudFormationStackSetInstanceImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(d.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("stack_set_name", stackSetName)
+	d.Set("account_id", accountId)
+	d.Set("region", region)
+	d.Set("retain_stack", false)
+	d.Set("stop_operation_on_timeout", false)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceAwsCloudFormationStackSetInstanceUpdate(d *schema.ResourceData, meta interface{}) error {
+	// retain_stack is only used on delete
+	if !d.HasChange("parameter_overrides") {
+		return resourceAwsCloudFormationStackSetInstanceRead(d, meta)
+	}
+
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(d.Id())
+	if err != nil {
+		return err
+	}
+
+	// Parameters left out of the list revert to the value of the stack set,
+	// so an empty list removes all overrides
+	operationId := resource.UniqueId()
+	input := &cloudformation.UpdateStackInstancesInput{
+		StackSetName:       aws.String(stackSetName),
+		Accounts:           []*string{aws.String(accountId)},
+		Regions:            []*string{aws.String(region)},
+		OperationId:        aws.String(operationId),
+		ParameterOverrides: make([]*cloudformation.Parameter, 0),
+	}
+	if v, ok := d.GetOk("parameter_overrides"); ok {
+		input.ParameterOverrides = expandCloudFormationParameters(v.(map[string]interface{}))
+	}
+
+	timeout, err := waitForCloudFormationDeploymentWindow(d, d.Timeout(schema.TimeoutUpdate))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] Updating CloudFormation stack set instance: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.UpdateStackInstances(input)
+		return err
+	})
+	if err != nil {
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			return fmt.Errorf("Updating CloudFormation stack set instance %q failed: %s", d.Id(), err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+	}
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, "update", timeout,
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] CloudFormation stack set instance %q updated", d.Id())
+
+	return resourceAwsCloudFormationStackSetInstanceRead(d, meta)
+}
+
+func resourceAwsCloudFormationStackSetInstanceDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).cfconn
+
+	stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(d.Id())
+	if err != nil {
+		return err
+	}
+
+	// Only the stack instance of this account and region is deleted,
+	// all other instances of the stack set are left untouched
+	operationId := resource.UniqueId()
+	input := &cloudformation.DeleteStackInstancesInput{
+		StackSetName: aws.String(stackSetName),
+		Accounts:     []*string{aws.String(accountId)},
+		Regions:      []*string{aws.String(region)},
+		OperationId:  aws.String(operationId),
+		RetainStacks: aws.Bool(d.Get("retain_stack").(bool)),
+	}
+
+	log.Printf("[DEBUG] Deleting CloudFormation stack set instance: %s", input)
+	err = retryCloudFormationStackSetOperation(conn, cloudFormationStackSetOperationConflictTimeout(d), func() error {
+		_, err := conn.DeleteStackInstances(input)
+		return err
+	})
+	if err != nil {
+		if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+			return nil
+		}
+		if !isCloudFormationStackSetOperationAlreadyStarted(err) {
+			return fmt.Errorf("Deleting CloudFormation stack set instance %q failed: %s", d.Id(), err)
+		}
+		log.Printf("[DEBUG] CloudFormation stack set %q operation %q already started: %s", stackSetName, operationId, err)
+	}
+
+	err = waitForCloudFormationStackSetOperationWithin(conn, stackSetName, operationId, "delete", d.Timeout(schema.TimeoutDelete),
+		cloudFormationStackSetOperationTimeout(d), d.Get("stop_operation_on_timeout").(bool))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] CloudFormation stack set instance %q has been deleted", d.Id())
+
+	return nil
+}
+
+func resourceAwsCloudFormationStackSetInstanceParseId(id string) (stackSetName, accountId, region string, err error) {
+	parts := strings.Split(id, ",")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		err = fmt.Errorf("stack_set_instance id must be of the form <stack set name>,<account id>,<region>, got %q", id)
+		return
+	}
+
+	stackSetName = parts[0]
+	accountId = parts[1]
+	region = parts[2]
+	return
+}
+
+// isCloudFormationStackSetOperationAlreadyStarted returns true when a retried
+// request was rejected because the operation it started is already underway.
+// The SDK retries transient failures with the same operation ID, so the
+// first attempt may have reached CloudFormation even though it errored.
+func isCloudFormationStackSetOperationAlreadyStarted(err error) bool {
+	return isAWSErr(err, cloudformation.ErrCodeOperationIdAlreadyExistsException, "") ||
+		isAWSErr(err, cloudformation.ErrCodeTokenAlreadyExistsException, "")
+}
@@ -0,0 +1,543 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccAWSCloudFormationStackSetInstance_basic(t *testing.T) {
+	var stackInstance cloudformation.StackInstance
+	stackSetName := fmt.Sprintf("tf-acc-test-instance-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetInstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetInstanceConfig(stackSetName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetInstanceExists("aws_cloudformation_stack_set_instance.test", &stackInstance),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set_instance.test", "stack_set_name", stackSetName),
+					resource.TestCheckResourceAttrPair("aws_cloudformation_stack_set_instance.test", "account_id", "data.aws_caller_identity.current", "account_id"),
+					resource.TestCheckResourceAttrPair("aws_cloudformation_stack_set_instance.test", "region", "data.aws_region.current", "name"),
+					resource.TestCheckResourceAttrSet("aws_cloudformation_stack_set_instance.test", "stack_id"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set_instance.test", "needs_update", "false"),
+				),
+			},
+			{
+				ResourceName:      "aws_cloudformation_stack_set_instance.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccAWSCloudFormationStackSetInstance_deleteSingleRegion(t *testing.T) {
+	var stackInstance cloudformation.StackInstance
+	stackSetName := fmt.Sprintf("tf-acc-test-instance-delete-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetInstanceDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetInstanceConfig_regions(stackSetName, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetInstanceExists("aws_cloudformation_stack_set_instance.east", &stackInstance),
+					testAccCheckCloudFormationStackSetInstanceExists("aws_cloudformation_stack_set_instance.west", &stackInstance),
+				),
+			},
+			{
+				Config: testAccAWSCloudFormationStackSetInstanceConfig_regions(stackSetName, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetInstanceExists("aws_cloudformation_stack_set_instance.east", &stackInstance),
+					testAccCheckCloudFormationStackSetInstanceRegionDestroyed("aws_cloudformation_stack_set_instance.east", "us-west-2"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsCloudFormationStackSetInstanceDelete_singleRegion(t *testing.T) {
+	cases := []struct {
+		RetainStack bool
+		Expected    string
+	}{
+		{RetainStack: false, Expected: "false"},
+		{RetainStack: true, Expected: "true"},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"DeleteStackInstances": {
+				{StatusCode: 200, Body: testCloudFormationDeleteStackInstancesResponse, ContentType: "text/xml"},
+			},
+			"DescribeStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := resourceAwsCloudFormationStackSetInstance()
+		d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+			"stack_set_name": "tf-test",
+			"account_id":     "123456789012",
+			"region":         "us-west-2",
+			"retain_stack":   tc.RetainStack,
+		})
+		d.SetId("tf-test,123456789012,us-west-2")
+
+		_, err = r.Apply(d.State(), &terraform.InstanceDiff{Destroy: true}, &AWSClient{cfconn: conn})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		deletes := requests["DeleteStackInstances"]
+		if len(deletes) != 1 {
+			t.Fatalf("%d: Expected a single DeleteStackInstances request, received %d", i, len(deletes))
+		}
+		input := deletes[0]
+		if v := input["Accounts.member.1"]; !reflect.DeepEqual(v, []string{"123456789012"}) || input.Get("Accounts.member.2") != "" {
+			t.Fatalf("%d: Expected only account 123456789012 to be deleted, received: %v", i, input)
+		}
+		if v := input["Regions.member.1"]; !reflect.DeepEqual(v, []string{"us-west-2"}) || input.Get("Regions.member.2") != "" {
+			t.Fatalf("%d: Expected only region us-west-2 to be deleted, received: %v", i, input)
+		}
+		if v := input.Get("RetainStacks"); v != tc.Expected {
+			t.Fatalf("%d: Expected RetainStacks %q, received: %q", i, tc.Expected, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetInstanceUpdate_parameterOverrides(t *testing.T) {
+	cases := []struct {
+		Overrides map[string]interface{}
+		Expected  map[string]string
+	}{
+		{
+			Overrides: map[string]interface{}{"VpcCidr": "10.1.0.0/16"},
+			Expected: map[string]string{
+				"ParameterOverrides.member.1.ParameterKey":   "VpcCidr",
+				"ParameterOverrides.member.1.ParameterValue": "10.1.0.0/16",
+			},
+		},
+		{
+			// Removing all overrides reverts to the stack set parameters
+			Overrides: map[string]interface{}{},
+			Expected: map[string]string{
+				"ParameterOverrides": "",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"UpdateStackInstances": {
+				{StatusCode: 200, Body: testCloudFormationUpdateStackInstancesResponse, ContentType: "text/xml"},
+			},
+			"DescribeStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), ContentType: "text/xml"},
+			},
+			"DescribeStackInstance": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackInstanceResponse(cloudformation.StackInstanceStatusCurrent), ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := resourceAwsCloudFormationStackSetInstance()
+		state := &terraform.InstanceState{
+			ID: "tf-test,123456789012,us-east-1",
+			Attributes: map[string]string{
+				"id":                          "tf-test,123456789012,us-east-1",
+				"stack_set_name":              "tf-test",
+				"account_id":                  "123456789012",
+				"region":                      "us-east-1",
+				"retain_stack":                "false",
+				"parameter_overrides.%":       "1",
+				"parameter_overrides.VpcCidr": "10.2.0.0/16",
+			},
+		}
+		rawConfig, err := config.NewRawConfig(map[string]interface{}{
+			"stack_set_name":      "tf-test",
+			"account_id":          "123456789012",
+			"region":              "us-east-1",
+			"parameter_overrides": tc.Overrides,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := &AWSClient{cfconn: conn}
+		diff, err := r.Diff(state, terraform.NewResourceConfig(rawConfig), meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff.RequiresNew() {
+			t.Fatalf("%d: Expected parameter_overrides to be updated in place, received: %#v", i, diff)
+		}
+		_, err = r.Apply(state, diff, meta)
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		updates := requests["UpdateStackInstances"]
+		if len(updates) != 1 {
+			t.Fatalf("%d: Expected a single UpdateStackInstances request, received %d", i, len(updates))
+		}
+		input := updates[0]
+		if v := input.Get("Accounts.member.1"); v != "123456789012" || input.Get("Accounts.member.2") != "" {
+			t.Fatalf("%d: Expected only account 123456789012 to be updated, received: %v", i, input)
+		}
+		if v := input.Get("Regions.member.1"); v != "us-east-1" || input.Get("Regions.member.2") != "" {
+			t.Fatalf("%d: Expected only region us-east-1 to be updated, received: %v", i, input)
+		}
+		for k, expected := range tc.Expected {
+			if _, ok := input[k]; !ok {
+				t.Fatalf("%d: Expected %s to be sent, received: %v", i, k, input)
+			}
+			if v := input.Get(k); v != expected {
+				t.Fatalf("%d: Expected %s to be %q, received: %q", i, k, expected, v)
+			}
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetInstanceImport(t *testing.T) {
+	r := resourceAwsCloudFormationStackSetInstance()
+	d := r.Data(nil)
+	d.SetId("tf-test,123456789012,us-east-1")
+
+	results, err := resourceAwsCloudFormationStackSetInstanceImport(d, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected a single resource, received %d", len(results))
+	}
+	expected := map[string]interface{}{
+		"stack_set_name":            "tf-test",
+		"account_id":                "123456789012",
+		"region":                    "us-east-1",
+		"retain_stack":              false,
+		"stop_operation_on_timeout": false,
+	}
+	for k, v := range expected {
+		if actual := results[0].Get(k); actual != v {
+			t.Fatalf("Expected %s to be %v, received: %v", k, v, actual)
+		}
+	}
+
+	d.SetId("tf-test,123456789012")
+	if _, err := resourceAwsCloudFormationStackSetInstanceImport(d, nil); err == nil {
+		t.Fatal("Expected an error importing an ID without region")
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetInstanceRead_needsUpdate(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackInstance": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackInstanceResponse(cloudformation.StackInstanceStatusCurrent), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationDescribeStackInstanceResponse(cloudformation.StackInstanceStatusOutdated), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationDescribeStackInstanceResponse(cloudformation.StackInstanceStatusCurrent), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSetInstance().Schema, map[string]interface{}{
+		"stack_set_name": "tf-test",
+		"account_id":     "123456789012",
+		"region":         "us-east-1",
+	})
+	d.SetId("tf-test,123456789012,us-east-1")
+
+	for i, expected := range []bool{false, true, false} {
+		if err := resourceAwsCloudFormationStackSetInstanceRead(d, &AWSClient{cfconn: conn}); err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+		if actual := d.Get("needs_update").(bool); actual != expected {
+			t.Fatalf("%d: Expected needs_update to be %t, received %t", i, expected, actual)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetInstanceParseId(t *testing.T) {
+	cases := []struct {
+		Id          string
+		ExpectError bool
+	}{
+		{Id: "stack-set,123456789012,us-east-1"},
+		{Id: "stack-set,123456789012", ExpectError: true},
+		{Id: "stack-set,,us-east-1", ExpectError: true},
+		{Id: "stack-set", ExpectError: true},
+	}
+
+	for _, tc := range cases {
+		stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(tc.Id)
+		if tc.ExpectError {
+			if err == nil {
+				t.Fatalf("expected %q to fail parsing", tc.Id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %s", tc.Id, err)
+		}
+		if stackSetName != "stack-set" || accountId != "123456789012" || region != "us-east-1" {
+			t.Fatalf("unexpected result parsing %q: %q, %q, %q", tc.Id, stackSetName, accountId, region)
+		}
+	}
+}
+
+func TestIsCloudFormationStackSetOperationAlreadyStarted(t *testing.T) {
+	cases := []struct {
+		Err      error
+		Expected bool
+	}{
+		{
+			Err:      awserr.New(cloudformation.ErrCodeOperationIdAlreadyExistsException, "The specified operation ID already exists.", nil),
+			Expected: true,
+		},
+		{
+			Err:      awserr.New(cloudformation.ErrCodeTokenAlreadyExistsException, "A client request token already exists.", nil),
+			Expected: true,
+		},
+		{
+			Err:      awserr.New(cloudformation.ErrCodeOperationInProgressException, "Another Operation on StackSet is in progress", nil),
+			Expected: false,
+		},
+		{
+			Err:      fmt.Errorf("connection reset by peer"),
+			Expected: false,
+		},
+	}
+
+	for i, tc := range cases {
+		if actual := isCloudFormationStackSetOperationAlreadyStarted(tc.Err); actual != tc.Expected {
+			t.Fatalf("%d: expected %t for %s, got %t", i, tc.Expected, tc.Err, actual)
+		}
+	}
+}
+
+func testAccCheckCloudFormationStackSetInstanceExists(n string, stackInstance *cloudformation.StackInstance) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		conn := testAccProvider.Meta().(*AWSClient).cfconn
+		resp, err := conn.DescribeStackInstance(&cloudformation.DescribeStackInstanceInput{
+			StackSetName:         aws.String(stackSetName),
+			StackInstanceAccount: aws.String(accountId),
+			StackInstanceRegion:  aws.String(region),
+		})
+		if err != nil {
+			return err
+		}
+		if resp.StackInstance == nil {
+			return fmt.Errorf("CloudFormation stack set instance not found")
+		}
+
+		*stackInstance = *resp.StackInstance
+
+		return nil
+	}
+}
+
+// testAccCheckCloudFormationStackSetInstanceRegionDestroyed checks that the
+// stack set and account of the named instance have no instance in the region
+func testAccCheckCloudFormationStackSetInstanceRegionDestroyed(n, region string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		stackSetName, accountId, _, err := resourceAwsCloudFormationStackSetInstanceParseId(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		conn := testAccProvider.Meta().(*AWSClient).cfconn
+		_, err = conn.DescribeStackInstance(&cloudformation.DescribeStackInstanceInput{
+			StackSetName:         aws.String(stackSetName),
+			StackInstanceAccount: aws.String(accountId),
+			StackInstanceRegion:  aws.String(region),
+		})
+		if err != nil {
+			if isAWSErr(err, cloudformation.ErrCodeStackInstanceNotFoundException, "") {
+				return nil
+			}
+			return err
+		}
+
+		return fmt.Errorf("CloudFormation stack set instance in %s still exists", region)
+	}
+}
+
+func testAccCheckAWSCloudFormationStackSetInstanceDestroy(s *terraform.State) error {
+	conn := testAccProvider.Meta().(*AWSClient).cfconn
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "aws_cloudformation_stack_set_instance" {
+			continue
+		}
+
+		stackSetName, accountId, region, err := resourceAwsCloudFormationStackSetInstanceParseId(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn.DescribeStackInstance(&cloudformation.DescribeStackInstanceInput{
+			StackSetName:         aws.String(stackSetName),
+			StackInstanceAccount: aws.String(accountId),
+			StackInstanceRegion:  aws.String(region),
+		})
+		if err != nil {
+			if isAWSErr(err, cloudformation.ErrCodeStackInstanceNotFoundException, "") ||
+				isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+				continue
+			}
+			return err
+		}
+
+		return fmt.Errorf("CloudFormation stack set instance still exists: %q", rs.Primary.ID)
+	}
+
+	return nil
+}
+
+func testAccAWSCloudFormationStackSetInstanceConfig(stackSetName string) string {
+	return fmt.Sprintf(`
+data "aws_caller_identity" "current" {}
+
+data "aws_region" "current" {
+  current = true
+}
+
+resource "aws_cloudformation_stack_set" "test" {
+  name = "%s"
+
+  template_body = <<TEMPLATE
+{
+  "Resources" : {
+    "MyVPC": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : "10.0.0.0/16"
+      }
+    }
+  }
+}
+TEMPLATE
+}
+
+resource "aws_cloudformation_stack_set_instance" "test" {
+  stack_set_name = "${aws_cloudformation_stack_set.test.name}"
+  account_id     = "${data.aws_caller_identity.current.account_id}"
+  region         = "${data.aws_region.current.name}"
+}
+`, stackSetName)
+}
+
+func testAccAWSCloudFormationStackSetInstanceConfig_regions(stackSetName string, west bool) string {
+	config := fmt.Sprintf(`
+data "aws_caller_identity" "current" {}
+
+resource "aws_cloudformation_stack_set" "test" {
+  name = "%s"
+
+  template_body = <<TEMPLATE
+{
+  "Resources" : {
+    "MyVPC": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : "10.0.0.0/16"
+      }
+    }
+  }
+}
+TEMPLATE
+}
+
+resource "aws_cloudformation_stack_set_instance" "east" {
+  stack_set_name = "${aws_cloudformation_stack_set.test.name}"
+  account_id     = "${data.aws_caller_identity.current.account_id}"
+  region         = "us-east-1"
+}
+`, stackSetName)
+
+	if west {
+		config += `
+resource "aws_cloudformation_stack_set_instance" "west" {
+  stack_set_name = "${aws_cloudformation_stack_set.test.name}"
+  account_id     = "${data.aws_caller_identity.current.account_id}"
+  region         = "us-west-2"
+}
+`
+	}
+
+	return config
+}
+
+const testCloudFormationDeleteStackInstancesResponse = `<DeleteStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DeleteStackInstancesResult>
+    <OperationId>terraform-20171012000000000000000001</OperationId>
+  </DeleteStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DeleteStackInstancesResponse>`
+
+const testCloudFormationUpdateStackInstancesResponse = `<UpdateStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <UpdateStackInstancesResult>
+    <OperationId>terraform-20171012000000000000000001</OperationId>
+  </UpdateStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</UpdateStackInstancesResponse>`
+
+func testCloudFormationDescribeStackInstanceResponse(status string) string {
+	return fmt.Sprintf(`<DescribeStackInstanceResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStackInstanceResult>
+    <StackInstance>
+      <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+      <StackId>arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-0123/2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackId>
+      <Account>123456789012</Account>
+      <Region>us-east-1</Region>
+      <Status>%s</Status>
+    </StackInstance>
+  </DescribeStackInstanceResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DescribeStackInstanceResponse>`, status)
+}
@@ -0,0 +1,3117 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccAWSCloudFormationStackSet_importBasic(t *testing.T) {
+	stackSetName := fmt.Sprintf("tf-acc-test-basic-%s", acctest.RandString(10))
+
+	resourceName := "aws_cloudformation_stack_set.test"
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetConfig(stackSetName, "10.0.0.0/16"),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccAWSCloudFormationStackSet_basic(t *testing.T) {
+	var stackSet cloudformation.StackSet
+	stackSetName := fmt.Sprintf("tf-acc-test-basic-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetConfig(stackSetName, "10.0.0.0/16"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "name", stackSetName),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "description", "Terraform acceptance test"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "parameters.%", "1"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "parameters.VpcCIDR", "10.0.0.0/16"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "tags.%", "1"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "tags.Name", stackSetName),
+					resource.TestCheckResourceAttrSet("aws_cloudformation_stack_set.test", "stack_set_id"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "administration_role_name", "AWSCloudFormationStackSetAdministrationRole"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "execution_role_name", "AWSCloudFormationStackSetExecutionRole"),
+					resource.TestMatchResourceAttr("aws_cloudformation_stack_set.test", "template_body_hash", regexp.MustCompile("^[0-9a-f]{64}$")),
+					resource.TestMatchResourceAttr("aws_cloudformation_stack_set.test", "arn",
+						regexp.MustCompile(`^arn:[^:]+:cloudformation:[^:]+:\d{12}:stackset/`+stackSetName+`:`)),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAWSCloudFormationStackSet_update(t *testing.T) {
+	var stackSet cloudformation.StackSet
+	stackSetName := fmt.Sprintf("tf-acc-test-update-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetConfig(stackSetName, "10.0.0.0/16"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "parameters.VpcCIDR", "10.0.0.0/16"),
+				),
+			},
+			{
+				Config: testAccAWSCloudFormationStackSetConfig(stackSetName, "10.1.0.0/16"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "parameters.VpcCIDR", "10.1.0.0/16"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAWSCloudFormationStackSet_preventUpdate(t *testing.T) {
+	var stackSet cloudformation.StackSet
+	stackSetName := fmt.Sprintf("tf-acc-test-prevent-update-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetConfig_preventUpdate(stackSetName, "10.0.0.0/16"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "prevent_update", "true"),
+				),
+			},
+			{
+				Config: testAccAWSCloudFormationStackSetConfig_preventUpdate(stackSetName, "10.1.0.0/16"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					testAccCheckCloudFormationStackSetParameter(&stackSet, "VpcCIDR", "10.0.0.0/16"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "parameters.VpcCIDR", "10.0.0.0/16"),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccAWSCloudFormationStackSet_capabilities(t *testing.T) {
+	var stackSet cloudformation.StackSet
+	stackSetName := fmt.Sprintf("tf-acc-test-capabilities-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAWSCloudFormationStackSetConfig_capabilities(stackSetName, `"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					testAccCheckCloudFormationStackSetCapabilities(&stackSet, "CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "capabilities.#", "2"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "effective_capabilities.#", "2"),
+				),
+			},
+			{
+				Config: testAccAWSCloudFormationStackSetConfig_capabilities(stackSetName, `"CAPABILITY_IAM"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckCloudFormationStackSetExists("aws_cloudformation_stack_set.test", &stackSet),
+					testAccCheckCloudFormationStackSetCapabilities(&stackSet, "CAPABILITY_IAM"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "capabilities.#", "1"),
+					resource.TestCheckResourceAttr("aws_cloudformation_stack_set.test", "effective_capabilities.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestCloudFormationStackSetArn(t *testing.T) {
+	cases := []struct {
+		Client   *AWSClient
+		Expected string
+	}{
+		{
+			Client:   &AWSClient{partition: "aws", region: "us-east-1", accountid: "123456789012"},
+			Expected: "arn:aws:cloudformation:us-east-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		},
+		{
+			Client:   &AWSClient{partition: "aws-cn", region: "cn-north-1", accountid: "123456789012"},
+			Expected: "arn:aws-cn:cloudformation:cn-north-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		},
+		{
+			Client:   &AWSClient{partition: "aws-us-gov", region: "us-gov-west-1", accountid: "123456789012"},
+			Expected: "arn:aws-us-gov:cloudformation:us-gov-west-1:123456789012:stackset/tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		},
+	}
+
+	for _, tc := range cases {
+		actual := cloudFormationStackSetArn(tc.Client, "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+		if actual != tc.Expected {
+			t.Fatalf("Expected %q, got %q", tc.Expected, actual)
+		}
+	}
+}
+
+func TestAccAWSCloudFormationStackSet_missingParameter(t *testing.T) {
+	stackSetName := fmt.Sprintf("tf-acc-test-missing-param-%s", acctest.RandString(10))
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckAWSCloudFormationStackSetDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAWSCloudFormationStackSetConfig_missingParameter(stackSetName),
+				ExpectError: regexp.MustCompile(`Parameters: \[VpcCIDR\] must have values`),
+			},
+		},
+	})
+}
+
+func TestCloudFormationMissingRequiredParameters(t *testing.T) {
+	declarations := []*cloudformation.ParameterDeclaration{
+		{
+			ParameterKey:  aws.String("VpcCIDR"),
+			ParameterType: aws.String("String"),
+		},
+		{
+			ParameterKey:  aws.String("InstanceType"),
+			ParameterType: aws.String("String"),
+			DefaultValue:  aws.String("t2.micro"),
+		},
+		{
+			ParameterKey:  aws.String("KeyName"),
+			ParameterType: aws.String("AWS::EC2::KeyPair::KeyName"),
+		},
+	}
+
+	cases := []struct {
+		Parameters map[string]interface{}
+		Expected   []string
+	}{
+		{
+			Parameters: map[string]interface{}{},
+			Expected:   []string{"KeyName", "VpcCIDR"},
+		},
+		{
+			Parameters: map[string]interface{}{"VpcCIDR": "10.0.0.0/16"},
+			Expected:   []string{"KeyName"},
+		},
+		{
+			Parameters: map[string]interface{}{"VpcCIDR": "10.0.0.0/16", "KeyName": "test"},
+			Expected:   nil,
+		},
+	}
+
+	for i, tc := range cases {
+		actual := cloudFormationMissingRequiredParameters(declarations, tc.Parameters)
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: expected missing parameters %q, got %q", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestCloudFormationTemplateHash(t *testing.T) {
+	// YAML templates are hashed as written, only JSON ones are normalized
+	cases := [][]string{
+		{
+			`{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"},"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+			`{
+  "Resources" : {
+    "Queue" : { "Type" : "AWS::SQS::Queue" },
+    "Topic" : { "Type" : "AWS::SNS::Topic" }
+  }
+}`,
+		},
+	}
+
+	for i, equivalent := range cases {
+		var hashes []string
+		for _, template := range equivalent {
+			normalized, err := normalizeCloudFormationTemplate(template)
+			if err != nil {
+				t.Fatalf("%d: unexpected error normalizing %q: %s", i, template, err)
+			}
+			hashes = append(hashes, cloudFormationTemplateHash(normalized))
+		}
+		if hashes[0] != hashes[1] {
+			t.Fatalf("%d: expected equivalent templates to have the same hash, got %q", i, hashes)
+		}
+		if len(hashes[0]) != 64 {
+			t.Fatalf("%d: expected a SHA-256 hex digest, got %q", i, hashes[0])
+		}
+	}
+
+	if cloudFormationTemplateHash(`{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`) == cloudFormationTemplateHash(`{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`) {
+		t.Fatal("expected different templates to have different hashes")
+	}
+}
+
+func TestCloudFormationDeploymentWindowDelay(t *testing.T) {
+	cases := []struct {
+		Window      string
+		Now         string
+		Expected    time.Duration
+		ExpectError bool
+	}{
+		// Open
+		{Window: "10:00-11:00", Now: "2017-10-12T10:30:00Z", Expected: 0},
+		{Window: "22:00-04:00", Now: "2017-10-12T23:30:00Z", Expected: 0},
+		{Window: "22:00-04:00", Now: "2017-10-12T01:00:00Z", Expected: 0},
+		// Opening in the near future
+		{Window: "10:01-11:00", Now: "2017-10-12T10:00:30Z", Expected: 30 * time.Second},
+		{Window: "22:00-04:00", Now: "2017-10-12T21:00:00Z", Expected: time.Hour},
+		// Closed for today
+		{Window: "10:00-11:00", Now: "2017-10-12T11:00:00Z", Expected: 23 * time.Hour},
+		{Window: "22:00-04:00", Now: "2017-10-12T04:00:00Z", Expected: 18 * time.Hour},
+		// Times are in UTC
+		{Window: "10:00-11:00", Now: "2017-10-12T12:30:00+02:00", Expected: 0},
+		{Window: "10:00-10:00", Now: "2017-10-12T10:00:00Z", ExpectError: true},
+		{Window: "10:00", Now: "2017-10-12T10:00:00Z", ExpectError: true},
+	}
+
+	for _, tc := range cases {
+		now, err := time.Parse(time.RFC3339, tc.Now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delay, err := cloudFormationDeploymentWindowDelay(tc.Window, now)
+		if tc.ExpectError {
+			if err == nil {
+				t.Fatalf("expected window %q to be invalid", tc.Window)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for window %q: %s", tc.Window, err)
+		}
+		if delay != tc.Expected {
+			t.Fatalf("expected window %q to open in %s at %s, got %s", tc.Window, tc.Expected, tc.Now, delay)
+		}
+	}
+}
+
+func TestWaitForCloudFormationDeploymentWindow(t *testing.T) {
+	r := resourceAwsCloudFormationStackSet()
+	now := time.Now().UTC()
+
+	open := fmt.Sprintf("%s-%s", now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"))
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":              "tf-test",
+		"deployment_window": open,
+	})
+	timeout, err := waitForCloudFormationDeploymentWindow(d, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error for open window %q: %s", open, err)
+	}
+	if timeout != 30*time.Minute {
+		t.Fatalf("expected the full timeout to remain for open window %q, got %s", open, timeout)
+	}
+
+	later := fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04"))
+	d = schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":              "tf-test",
+		"deployment_window": later,
+	})
+	if _, err := waitForCloudFormationDeploymentWindow(d, 30*time.Minute); err == nil {
+		t.Fatalf("expected window %q opening after the timeout to fail", later)
+	}
+}
+
+func TestCloudFormationStackSetRunningOperationId(t *testing.T) {
+	created := func(hour int) *time.Time {
+		return aws.Time(time.Date(2017, 10, 12, hour, 0, 0, 0, time.UTC))
+	}
+
+	cases := []struct {
+		Summaries []*cloudformation.StackSetOperationSummary
+		Expected  string
+	}{
+		{
+			Summaries: nil,
+			Expected:  "",
+		},
+		{
+			Summaries: []*cloudformation.StackSetOperationSummary{
+				{
+					OperationId:       aws.String("op-2"),
+					Status:            aws.String(cloudformation.StackSetOperationStatusFailed),
+					CreationTimestamp: created(2),
+				},
+				{
+					OperationId:       aws.String("op-1"),
+					Status:            aws.String(cloudformation.StackSetOperationStatusSucceeded),
+					CreationTimestamp: created(1),
+				},
+			},
+			Expected: "",
+		},
+		// The listed order isn't trusted
+		{
+			Summaries: []*cloudformation.StackSetOperationSummary{
+				{
+					OperationId:       aws.String("op-1"),
+					Status:            aws.String(cloudformation.StackSetOperationStatusSucceeded),
+					CreationTimestamp: created(1),
+				},
+				{
+					OperationId:       aws.String("op-2"),
+					Status:            aws.String(cloudformation.StackSetOperationStatusRunning),
+					CreationTimestamp: created(2),
+				},
+			},
+			Expected: "op-2",
+		},
+		{
+			Summaries: []*cloudformation.StackSetOperationSummary{
+				{
+					OperationId:       aws.String("op-3"),
+					Status:            aws.String(cloudformation.StackSetOperationStatusStopping),
+					CreationTimestamp: created(3),
+				},
+			},
+			Expected: "op-3",
+		},
+	}
+
+	for i, tc := range cases {
+		if actual := cloudFormationStackSetRunningOperationId(tc.Summaries); actual != tc.Expected {
+			t.Fatalf("%d: expected running operation %q, got %q", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestFindCloudFormationStackSetRunningOperationId_pages(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsPageResponse("page-2",
+				"terraform-20171012000000000000000001", "SUCCEEDED", "2017-10-12T10:00:00Z",
+			), "text/xml"},
+			{200, testCloudFormationListStackSetOperationsPageResponse("",
+				"terraform-20171012000000000000000002", "RUNNING", "2017-10-12T11:00:00Z",
+			), "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	id, err := findCloudFormationStackSetRunningOperationId(conn, "tf-test")
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if id != "terraform-20171012000000000000000002" {
+		t.Fatalf("Expected the running operation of the second page, received: %q", id)
+	}
+	if n := len(requests["ListStackSetOperations"]); n != 2 {
+		t.Fatalf("Expected all operations to be paged through, received %d ListStackSetOperations requests", n)
+	}
+}
+
+func TestFindCloudFormationStackSetRunningOperationId_throttling(t *testing.T) {
+	throttled := &awsMockResponse{400, testCloudFormationErrorResponse("Throttling", "Rate exceeded"), "text/xml"}
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsPageResponse("page-2"), "text/xml"},
+			throttled,
+			throttled,
+			{200, testCloudFormationListStackSetOperationsPageResponse("",
+				"terraform-20171012000000000000000002", "RUNNING", "2017-10-12T11:00:00Z",
+			), "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+	// Leave retrying to the stack set code rather than the SDK
+	conn.Retryer = client.DefaultRetryer{NumMaxRetries: 0}
+
+	id, err := findCloudFormationStackSetRunningOperationId(conn, "tf-test")
+	if err != nil {
+		t.Fatalf("Expected throttling to be retried, received: %s", err)
+	}
+	if id != "terraform-20171012000000000000000002" {
+		t.Fatalf("Expected the running operation after the empty first page, received: %q", id)
+	}
+
+	pages := requests["ListStackSetOperations"]
+	if len(pages) != 4 {
+		t.Fatalf("Expected 4 ListStackSetOperations requests, received %d", len(pages))
+	}
+	for i := 1; i < len(pages); i++ {
+		if v := pages[i].Get("NextToken"); v != "page-2" {
+			t.Fatalf("Expected request %d to retry the second page, received NextToken %q", i, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_maxRetries(t *testing.T) {
+	inProgress := &awsMockResponse{400, testCloudFormationErrorResponse(cloudformation.ErrCodeOperationInProgressException, "Another Operation on StackSet tf-test is in progress"), "text/xml"}
+	responses := map[string][]*awsMockResponse{}
+	for k, v := range testCloudFormationStackSetUpdateResponses {
+		responses[k] = v
+	}
+	responses["UpdateStackSet"] = []*awsMockResponse{inProgress}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+	// The provider's max_retries
+	conn.Retryer = client.DefaultRetryer{NumMaxRetries: 1}
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+	})
+	if err == nil || !strings.Contains(err.Error(), cloudformation.ErrCodeOperationInProgressException) {
+		t.Fatalf("Expected OperationInProgressException after exhausting the retries, received: %s", err)
+	}
+	if n := len(requests["UpdateStackSet"]); n != 2 {
+		t.Fatalf("Expected UpdateStackSet to be retried once, received %d requests", n)
+	}
+
+	otherErr := awserr.New(cloudformation.ErrCodeInvalidOperationException, "The specified operation isn't valid.", nil)
+	calls := 0
+	err = retryCloudFormationStackSetOperation(conn, 0, func() error {
+		calls++
+		return otherErr
+	})
+	if err != otherErr || calls != 1 {
+		t.Fatalf("Expected other errors not to be retried, received %d calls and: %s", calls, err)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_operationConflictTimeout(t *testing.T) {
+	inProgress := &awsMockResponse{400, testCloudFormationErrorResponse(cloudformation.ErrCodeOperationInProgressException, "Another Operation on StackSet tf-test is in progress"), "text/xml"}
+	responses := map[string][]*awsMockResponse{}
+	for k, v := range testCloudFormationStackSetUpdateResponses {
+		responses[k] = v
+	}
+	responses["UpdateStackSet"] = []*awsMockResponse{
+		inProgress,
+		inProgress,
+		{200, testCloudFormationUpdateStackSetResponse, "text/xml"},
+	}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+	// The conflict timeout takes precedence over max_retries
+	conn.Retryer = client.DefaultRetryer{NumMaxRetries: 0}
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":                                  "tf-test",
+		"template_body":                         `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+		"operation_conflict_timeout_in_minutes": 1,
+	})
+	if err != nil {
+		t.Fatalf("Expected the update to wait for the conflicting operation, received: %s", err)
+	}
+	if n := len(requests["UpdateStackSet"]); n != 3 {
+		t.Fatalf("Expected UpdateStackSet to be retried until the conflict cleared, received %d requests", n)
+	}
+}
+
+func TestRetryCloudFormationStackSetOperation_conflictTimeout(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	conflictErr := awserr.New(cloudformation.ErrCodeOperationInProgressException, "Another Operation on StackSet tf-test is in progress", nil)
+	calls := 0
+	err = retryCloudFormationStackSetOperation(conn, time.Second, func() error {
+		calls++
+		return conflictErr
+	})
+	if err != conflictErr {
+		t.Fatalf("Expected the conflict to be returned once the timeout elapsed, received: %v", err)
+	}
+	if calls < 2 {
+		t.Fatalf("Expected the conflict to be retried, received %d calls", calls)
+	}
+
+	otherErr := awserr.New(cloudformation.ErrCodeInvalidOperationException, "The specified operation isn't valid.", nil)
+	calls = 0
+	err = retryCloudFormationStackSetOperation(conn, time.Minute, func() error {
+		calls++
+		return otherErr
+	})
+	if err != otherErr || calls != 1 {
+		t.Fatalf("Expected other errors not to be retried, received %d calls and: %s", calls, err)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_retriesReuseOperationId(t *testing.T) {
+	responses := map[string][]*awsMockResponse{}
+	for k, v := range testCloudFormationStackSetUpdateResponses {
+		responses[k] = v
+	}
+	// Retried by the SDK, by the stack set code and finally found started
+	responses["UpdateStackSet"] = []*awsMockResponse{
+		{500, testCloudFormationErrorResponse("InternalFailure", "We encountered an internal error. Please try again."), "text/xml"},
+		{400, testCloudFormationErrorResponse(cloudformation.ErrCodeOperationInProgressException, "Another Operation on StackSet tf-test is in progress"), "text/xml"},
+		{400, testCloudFormationErrorResponse(cloudformation.ErrCodeOperationIdAlreadyExistsException, "The operation ID already exists"), "text/xml"},
+	}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+	conn.Retryer = client.DefaultRetryer{NumMaxRetries: 2}
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected the already started operation to be awaited, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 3 {
+		t.Fatalf("Expected UpdateStackSet to be retried twice, received %d requests", len(updates))
+	}
+	operationId := updates[0].Get("OperationId")
+	if operationId == "" {
+		t.Fatal("Expected an OperationId to be sent")
+	}
+	for i, update := range updates {
+		if v := update.Get("OperationId"); v != operationId {
+			t.Fatalf("Expected request %d to reuse OperationId %q, received: %q", i, operationId, v)
+		}
+	}
+	for i, describe := range requests["DescribeStackSetOperation"] {
+		if v := describe.Get("OperationId"); v != operationId {
+			t.Fatalf("Expected DescribeStackSetOperation request %d to wait for the single operation %q, received: %q", i, operationId, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_sequentialUpdates(t *testing.T) {
+	responses := map[string][]*awsMockResponse{}
+	for k, v := range testCloudFormationStackSetUpdateResponses {
+		responses[k] = v
+	}
+	// The first update's operation has long succeeded while the second
+	// update's operation is still running on its first check
+	responses["DescribeStackSetOperation"] = []*awsMockResponse{
+		{200, testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), "text/xml"},
+		{200, testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusRunning), "text/xml"},
+		{200, testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), "text/xml"},
+	}
+
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	templates := []string{
+		`{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		`{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+		`{"Resources":{"Bucket":{"Type":"AWS::S3::Bucket"}}}`,
+	}
+	for i := 1; i < len(templates); i++ {
+		err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": templates[i-1],
+		}, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": templates[i],
+		})
+		if err != nil {
+			t.Fatalf("Expected update %d to succeed, received: %s", i, err)
+		}
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 2 {
+		t.Fatalf("Expected two UpdateStackSet requests, received %d", len(updates))
+	}
+	first, second := updates[0].Get("OperationId"), updates[1].Get("OperationId")
+	if first == "" || first == second {
+		t.Fatalf("Expected each update to start its own operation, received: %q and %q", first, second)
+	}
+
+	describes := requests["DescribeStackSetOperation"]
+	if len(describes) != 3 {
+		t.Fatalf("Expected the second update to wait for its running operation, received %d DescribeStackSetOperation requests", len(describes))
+	}
+	for i, expected := range []string{first, second, second} {
+		if v := describes[i].Get("OperationId"); v != expected {
+			t.Fatalf("Expected DescribeStackSetOperation request %d to check operation %q, received: %q", i, expected, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_waitsForRunningOperation(t *testing.T) {
+	const runningOperationId = "terraform-20171012000000000000000001"
+
+	// An interrupted apply left its operation running, which finishes on
+	// the second check
+	var mu sync.Mutex
+	var order []string
+	runningChecks := 0
+	closeFunc, sess, err := getMockedAwsApiSessionWithResponder("CloudFormation", func(r *http.Request, requestBody string) *awsMockResponse {
+		params, _ := url.ParseQuery(requestBody)
+		action := params.Get("Action")
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch action {
+		case "ListStackSetOperations":
+			status := cloudformation.StackSetOperationStatusRunning
+			if runningChecks > 1 {
+				status = cloudformation.StackSetOperationStatusSucceeded
+			}
+			order = append(order, action+" "+status)
+			return &awsMockResponse{200, testCloudFormationListStackSetOperationsPageResponse("",
+				runningOperationId, status, "2017-10-12T10:00:00Z"), "text/xml"}
+		case "DescribeStackSetOperation":
+			status := cloudformation.StackSetOperationStatusSucceeded
+			if params.Get("OperationId") == runningOperationId {
+				runningChecks++
+				if runningChecks == 1 {
+					status = cloudformation.StackSetOperationStatusRunning
+				}
+			}
+			order = append(order, action+" "+params.Get("OperationId")+" "+status)
+			return &awsMockResponse{200, testCloudFormationDescribeStackSetOperationResponse(status), "text/xml"}
+		}
+
+		order = append(order, action)
+		if responses, ok := testCloudFormationStackSetUpdateResponses[action]; ok {
+			return responses[0]
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, cloudformation.New(sess), map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{
+		"ListStackSetOperations RUNNING",
+		"DescribeStackSetOperation " + runningOperationId + " RUNNING",
+		"DescribeStackSetOperation " + runningOperationId + " SUCCEEDED",
+		"UpdateStackSet",
+	}
+	if len(order) < len(expected) || !reflect.DeepEqual(order[:len(expected)], expected) {
+		t.Fatalf("Expected the update to start once the running operation succeeded, received requests: %q", order)
+	}
+}
+
+const testCloudFormationConstrainedParametersTemplate = `{
+  "Parameters": {
+    "Environment": {"Type": "String", "AllowedValues": ["dev", "prod"]},
+    "BucketPrefix": {"Type": "String", "AllowedPattern": "[a-z0-9-]+", "MinLength": 3, "MaxLength": "10"},
+    "Port": {"Type": "Number", "AllowedValues": [80, 443]},
+    "Subnets": {"Type": "CommaDelimitedList", "AllowedValues": ["a", "b"]}
+  },
+  "Resources": {"Topic": {"Type": "AWS::SNS::Topic"}}
+}`
+
+func TestCloudFormationParameterConstraintViolations(t *testing.T) {
+	cases := []struct {
+		Template   string
+		Parameters map[string]interface{}
+		Expected   []string
+	}{
+		{
+			Template: testCloudFormationConstrainedParametersTemplate,
+			Parameters: map[string]interface{}{
+				"Environment":  "prod",
+				"BucketPrefix": "tf-test",
+				"Port":         "443",
+				"Subnets":      "a,b",
+			},
+		},
+		{
+			Template: testCloudFormationConstrainedParametersTemplate,
+			Parameters: map[string]interface{}{
+				"Environment":  "staging",
+				"BucketPrefix": "TF",
+				"Port":         "8080",
+			},
+			Expected: []string{
+				`BucketPrefix: "TF" does not match the AllowedPattern "[a-z0-9-]+"`,
+				`BucketPrefix: "TF" is shorter than the MinLength of 3`,
+				`Environment: "staging" is not one of the AllowedValues ["dev" "prod"]`,
+				`Port: "8080" is not one of the AllowedValues ["80" "443"]`,
+			},
+		},
+		{
+			Template: testCloudFormationConstrainedParametersTemplate,
+			Parameters: map[string]interface{}{
+				"BucketPrefix": "tf-test-bucket",
+			},
+			Expected: []string{
+				`BucketPrefix: "tf-test-bucket" is longer than the MaxLength of 10`,
+			},
+		},
+		{
+			Template: `Parameters:
+  Environment:
+    Type: String
+    AllowedValues:
+      - dev
+      - prod
+    MaxLength: 4
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+`,
+			Parameters: map[string]interface{}{
+				"Environment": "staging",
+			},
+			Expected: []string{
+				`Environment: "staging" is longer than the MaxLength of 4`,
+				`Environment: "staging" is not one of the AllowedValues ["dev" "prod"]`,
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		actual, err := cloudFormationParameterConstraintViolations(tc.Template, tc.Parameters)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: expected violations %q, received %q", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetCustomizeDiff_parameterConstraints(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	_, _, err = testCloudFormationStackSetDiff(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": testCloudFormationConstrainedParametersTemplate,
+		"parameters":    map[string]interface{}{"Environment": "dev"},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": testCloudFormationConstrainedParametersTemplate,
+		"parameters":    map[string]interface{}{"Environment": "staging"},
+	})
+	if err == nil {
+		t.Fatal("Expected plan to fail for a parameter violating its constraints")
+	}
+	if !strings.Contains(err.Error(), `Environment: "staging" is not one of the AllowedValues`) {
+		t.Fatalf("Expected the error to name the violated constraint, received: %s", err)
+	}
+	if n := len(requests["UpdateStackSet"]); n != 0 {
+		t.Fatalf("Expected no UpdateStackSet request, received %d", n)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetCustomizeDiff_operationPreferences(t *testing.T) {
+	cases := []struct {
+		Preferences map[string]interface{}
+		ExpectError string
+	}{
+		{
+			Preferences: map[string]interface{}{"failure_tolerance_count": 1, "max_concurrent_percentage": 50},
+		},
+		{
+			Preferences: map[string]interface{}{"failure_tolerance_count": 1, "failure_tolerance_percentage": 10},
+			ExpectError: "only one of failure_tolerance_count or failure_tolerance_percentage",
+		},
+		{
+			Preferences: map[string]interface{}{"max_concurrent_count": 2, "max_concurrent_percentage": 50},
+			ExpectError: "only one of max_concurrent_count or max_concurrent_percentage",
+		},
+	}
+
+	closeFunc, conn, _, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	for i, tc := range cases {
+		_, _, err := testCloudFormationStackSetDiff(t, conn, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		}, map[string]interface{}{
+			"name":                  "tf-test",
+			"template_body":         `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			"operation_preferences": []interface{}{tc.Preferences},
+		})
+		if tc.ExpectError == "" {
+			if err != nil {
+				t.Fatalf("%d: Expected no error, received: %s", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.ExpectError) {
+			t.Fatalf("%d: Expected error containing %q, received: %v", i, tc.ExpectError, err)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_operationPreferences(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+		"operation_preferences": []interface{}{map[string]interface{}{
+			"failure_tolerance_count":   1,
+			"max_concurrent_percentage": 50,
+			"region_order":              []interface{}{"eu-west-1", "us-east-1"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single UpdateStackSet request, received %d", len(updates))
+	}
+	expected := map[string]string{
+		"OperationPreferences.FailureToleranceCount":   "1",
+		"OperationPreferences.MaxConcurrentPercentage": "50",
+		"OperationPreferences.RegionOrder.member.1":    "eu-west-1",
+		"OperationPreferences.RegionOrder.member.2":    "us-east-1",
+	}
+	for k, v := range expected {
+		if actual := updates[0].Get(k); actual != v {
+			t.Fatalf("Expected %s to be %q, received: %q", k, v, actual)
+		}
+	}
+	for _, k := range []string{"OperationPreferences.FailureTolerancePercentage", "OperationPreferences.MaxConcurrentCount"} {
+		if _, ok := updates[0][k]; ok {
+			t.Fatalf("Expected unset %s not to be sent, received: %v", k, updates[0])
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetDiff_operationPreferencesOnly(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	oldConfig := map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"operation_preferences": []interface{}{map[string]interface{}{
+			"failure_tolerance_count":   1,
+			"max_concurrent_percentage": 25,
+		}},
+	}
+	newConfig := map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"operation_preferences": []interface{}{map[string]interface{}{
+			"failure_tolerance_count":   1,
+			"max_concurrent_percentage": 50,
+		}},
+	}
+
+	state, diff, err := testCloudFormationStackSetDiff(t, conn, oldConfig, newConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Computed attributes missing from the test state are left out
+	var changed []string
+	for k, v := range diff.Attributes {
+		if !v.NewComputed {
+			changed = append(changed, k)
+		}
+	}
+	if len(changed) != 1 || changed[0] != "operation_preferences.0.max_concurrent_percentage" {
+		t.Fatalf("Expected only max_concurrent_percentage to change, received: %v", changed)
+	}
+
+	// The preferences only apply to the next stack set operation
+	if _, err := resourceAwsCloudFormationStackSet().Apply(state, diff, &AWSClient{cfconn: conn}); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if n := len(requests["UpdateStackSet"]); n != 0 {
+		t.Fatalf("Expected no UpdateStackSet requests, received %d", n)
+	}
+}
+
+func TestCloudFormationUnnecessaryCapabilities(t *testing.T) {
+	cases := []struct {
+		Template     string
+		Capabilities []string
+		Expected     []string
+	}{
+		{
+			Template:     `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			Capabilities: []string{"CAPABILITY_NAMED_IAM"},
+			Expected:     []string{"CAPABILITY_NAMED_IAM"},
+		},
+		{
+			Template: `Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+`,
+			Capabilities: []string{"CAPABILITY_NAMED_IAM", "CAPABILITY_IAM"},
+			Expected:     []string{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+		},
+		{
+			Template:     `{"Resources":{"Role":{"Type":"AWS::IAM::Role","Properties":{"RoleName":"tf-test"}}}}`,
+			Capabilities: []string{"CAPABILITY_NAMED_IAM"},
+		},
+		{
+			Template: `Resources:
+  Role:
+    Type: AWS::IAM::Role
+`,
+			Capabilities: []string{"CAPABILITY_IAM"},
+		},
+		{
+			Template: `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		},
+	}
+
+	for i, tc := range cases {
+		actual, err := cloudFormationUnnecessaryCapabilities(tc.Template, tc.Capabilities)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: expected unnecessary capabilities %q, received %q", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestCloudFormationStackSetOperationError(t *testing.T) {
+	err := cloudFormationStackSetOperationError(awserr.New(cloudformation.ErrCodeInvalidOperationException, "The specified operation isn't valid.", nil))
+	if !strings.Contains(err.Error(), "The specified operation isn't valid.") {
+		t.Fatalf("expected original error message to be preserved, got: %s", err)
+	}
+	if !strings.Contains(err.Error(), "permission model") {
+		t.Fatalf("expected error to explain the permission model conflict, got: %s", err)
+	}
+
+	err = cloudFormationStackSetOperationError(awserr.New(cloudformation.ErrCodeStackSetNotEmptyException, "StackSet is not empty", nil))
+	if !strings.Contains(err.Error(), "StackSet is not empty") {
+		t.Fatalf("expected original error message to be preserved, got: %s", err)
+	}
+	if !strings.Contains(err.Error(), "aws_cloudformation_stack_set_instance") {
+		t.Fatalf("expected error to explain how to remove the stack instances, got: %s", err)
+	}
+
+	otherErr := awserr.New(cloudformation.ErrCodeStackSetNotFoundException, "StackSet not found", nil)
+	if err := cloudFormationStackSetOperationError(otherErr); err != otherErr {
+		t.Fatalf("expected unrelated error to be returned as is, got: %s", err)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetCreate_templateUrlRegion(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"CreateStackSet": {
+			{400, testCloudFormationErrorResponse("ValidationError", "S3 error: The bucket you are attempting to access must be addressed using the specified endpoint. "+
+				"Please send all future requests to this endpoint. For more information check http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html"), "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":         "tf-test",
+		"template_url": "https://tf-test-eu-west-1.s3.amazonaws.com/template.json",
+	})
+
+	err = resourceAwsCloudFormationStackSetCreate(d, &AWSClient{cfconn: conn, region: "us-east-1"})
+	if err == nil {
+		t.Fatal("Expected an error for a template in another region")
+	}
+	if !strings.Contains(err.Error(), "must be addressed using the specified endpoint") {
+		t.Fatalf("Expected the original error message to be preserved, got: %s", err)
+	}
+	if !strings.Contains(err.Error(), "same region as the stack set (us-east-1)") {
+		t.Fatalf("Expected the error to name the region mismatch, got: %s", err)
+	}
+
+	otherErr := awserr.New("ValidationError", "Template format error: JSON not well-formed.", nil)
+	if err := cloudFormationStackSetTemplateUrlError(otherErr, "us-east-1"); err != otherErr {
+		t.Fatalf("Expected unrelated error to be returned as is, got: %s", err)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_invalidParameterValue(t *testing.T) {
+	responses := map[string][]*awsMockResponse{}
+	for k, v := range testCloudFormationStackSetUpdateResponses {
+		responses[k] = v
+	}
+	responses["UpdateStackSet"] = []*awsMockResponse{
+		{400, testCloudFormationErrorResponse("ValidationError", "Parameter 'Port' must be a number."), "text/xml"},
+	}
+
+	closeFunc, conn, _, err := getMockedCloudFormationConn(responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Parameters":{"Port":{"Type":"Number"}},"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"parameters":    map[string]interface{}{"Port": "80"},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Parameters":{"Port":{"Type":"Number"}},"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"parameters":    map[string]interface{}{"Port": "eighty"},
+	})
+	if err == nil {
+		t.Fatal("Expected an error for a malformed parameter value")
+	}
+	if !strings.Contains(err.Error(), "Parameter 'Port' must be a number.") {
+		t.Fatalf("Expected the original error message to be preserved, got: %s", err)
+	}
+	if !strings.Contains(err.Error(), `The value of parameter "Port"`) {
+		t.Fatalf("Expected the error to name the parameter, got: %s", err)
+	}
+}
+
+func TestCloudFormationParameterValueError(t *testing.T) {
+	cases := []struct {
+		Err      error
+		Expected string
+	}{
+		{
+			Err:      awserr.New("ValidationError", "Parameter 'InstanceType' must be one of AllowedValues", nil),
+			Expected: `parameter "InstanceType"`,
+		},
+		{
+			Err:      awserr.New("ValidationError", "Parameter value sg-1234 for parameter name SecurityGroup does not exist. Rollback requested by user.", nil),
+			Expected: `parameter "SecurityGroup"`,
+		},
+	}
+
+	for i, tc := range cases {
+		err := cloudFormationParameterValueError(tc.Err)
+		if !strings.Contains(err.Error(), tc.Expected) {
+			t.Fatalf("%d: expected %q to contain %q", i, err, tc.Expected)
+		}
+	}
+
+	otherErr := awserr.New("ValidationError", "Parameters: [VPCCidr] must have values", nil)
+	if err := cloudFormationParameterValueError(otherErr); err != otherErr {
+		t.Fatalf("Expected unrelated error to be returned as is, got: %s", err)
+	}
+}
+
+func TestCloudFormationStackSetOperationResultFailure(t *testing.T) {
+	cases := []struct {
+		Result   *cloudformation.StackSetOperationResultSummary
+		Expected []string
+	}{
+		{
+			Result: &cloudformation.StackSetOperationResultSummary{
+				Account:      aws.String("123456789012"),
+				Region:       aws.String("us-west-2"),
+				Status:       aws.String(cloudformation.StackSetOperationResultStatusFailed),
+				StatusReason: aws.String("Resource handler returned message: VPC limit exceeded"),
+			},
+			Expected: []string{"account 123456789012 (us-west-2): Resource handler returned message: VPC limit exceeded"},
+		},
+		{
+			Result: &cloudformation.StackSetOperationResultSummary{
+				Account:      aws.String("123456789012"),
+				Region:       aws.String("eu-west-1"),
+				Status:       aws.String(cloudformation.StackSetOperationResultStatusFailed),
+				StatusReason: aws.String("Account 123456789012 should have 'AWSCloudFormationStackSetExecutionRole' role with trust relationship to Role 'AWSCloudFormationStackSetAdministrationRole'."),
+			},
+			Expected: []string{
+				"account 123456789012 (eu-west-1)",
+				"can't assume the execution role in account 123456789012",
+				"should have 'AWSCloudFormationStackSetExecutionRole' role",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		actual := cloudFormationStackSetOperationResultFailure(tc.Result)
+		for _, e := range tc.Expected {
+			if !strings.Contains(actual, e) {
+				t.Fatalf("%d: expected %q to contain %q", i, actual, e)
+			}
+		}
+	}
+}
+
+func TestCloudFormationStackSetOperationRefreshFunc_notFound(t *testing.T) {
+	notFound := testCloudFormationErrorResponse(cloudformation.ErrCodeOperationNotFoundException, "The specified operation ID does not exist.")
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSetOperation": {
+			{StatusCode: 404, Body: notFound, ContentType: "text/xml"},
+			{StatusCode: 404, Body: notFound, ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusRunning), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	refresh := cloudFormationStackSetOperationRefreshFunc(conn, "tf-test", "terraform-20171012000000000000000001")
+
+	for i := 0; i < 2; i++ {
+		operation, status, err := refresh()
+		if err != nil {
+			t.Fatalf("%d: Expected a not found operation to be retried, received: %s", i, err)
+		}
+		if operation != nil || status != "" {
+			t.Fatalf("%d: Expected no operation, received %v with status %q", i, operation, status)
+		}
+	}
+
+	operation, status, err := refresh()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if operation == nil || status != cloudformation.StackSetOperationStatusRunning {
+		t.Fatalf("Expected a RUNNING operation, received %v with status %q", operation, status)
+	}
+}
+
+func TestWaitForCloudFormationStackSetOperation_timeout(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSetOperation": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusRunning), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	for _, phase := range []string{"create", "update", "delete"} {
+		err := waitForCloudFormationStackSetOperation(conn, "tf-test", "terraform-20171012000000000000000001", phase, 500*time.Millisecond)
+		if err == nil {
+			t.Fatalf("%s: Expected a timeout error", phase)
+		}
+
+		for _, e := range []string{
+			"Timeout during " + phase + " of CloudFormation stack set \"tf-test\"",
+			"operation \"terraform-20171012000000000000000001\"",
+			"last observed status: RUNNING",
+		} {
+			if !strings.Contains(err.Error(), e) {
+				t.Fatalf("%s: Expected %q to contain %q", phase, err, e)
+			}
+		}
+	}
+}
+
+func TestWaitForCloudFormationStackSetOperationWithin_operationTimeout(t *testing.T) {
+	for _, stop := range []bool{false, true} {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"DescribeStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusRunning), ContentType: "text/xml"},
+			},
+			"StopStackSetOperation": {
+				{StatusCode: 200, Body: testCloudFormationStopStackSetOperationResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = waitForCloudFormationStackSetOperationWithin(conn, "tf-test", "terraform-20171012000000000000000001", "update", 30*time.Minute, 500*time.Millisecond, stop)
+		closeFunc()
+		if err == nil {
+			t.Fatalf("%t: Expected the operation timeout to be exceeded", stop)
+		}
+		if !strings.Contains(err.Error(), "did not finish within 500ms") {
+			t.Fatalf("%t: Expected the error to name the operation timeout, received: %s", stop, err)
+		}
+
+		stops := requests["StopStackSetOperation"]
+		if !stop {
+			if len(stops) != 0 {
+				t.Fatalf("Expected the operation not to be stopped, received %d requests", len(stops))
+			}
+			continue
+		}
+		if len(stops) != 1 || stops[0].Get("OperationId") != "terraform-20171012000000000000000001" {
+			t.Fatalf("Expected the operation to be stopped, received: %v", stops)
+		}
+		if !strings.Contains(err.Error(), "stop_operation_on_timeout") {
+			t.Fatalf("Expected the error to explain the stopped operation, received: %s", err)
+		}
+	}
+}
+
+func TestGetCloudFormationStackSetOperationFailures_pages(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"ListStackSetOperationResults": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationResultsResponse("page-2", "111111111111", "FAILED", "222222222222", "SUCCEEDED"), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationResultsResponse("page-3", "333333333333", "FAILED", "444444444444", "FAILED"), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationResultsResponse("", "555555555555", "FAILED", "666666666666", "SUCCEEDED"), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	failures, err := getCloudFormationStackSetOperationFailures(conn, "tf-test", "terraform-20171012000000000000000001")
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	expected := []string{
+		"account 111111111111 (us-east-1): Resource creation failed",
+		"account 333333333333 (us-east-1): Resource creation failed",
+		"account 444444444444 (us-east-1): Resource creation failed",
+		"account 555555555555 (us-east-1): Resource creation failed",
+	}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Fatalf("Expected failures %q, received %q", expected, failures)
+	}
+
+	pages := requests["ListStackSetOperationResults"]
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 ListStackSetOperationResults requests, received %d", len(pages))
+	}
+	for i, token := range []string{"", "page-2", "page-3"} {
+		if v := pages[i].Get("NextToken"); v != token {
+			t.Fatalf("Expected request %d to send NextToken %q, received %q", i, token, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetCreate_clientRequestToken(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"CreateStackSet": {
+			{500, testCloudFormationErrorResponse("InternalFailure", "We encountered an internal error."), "text/xml"},
+			{400, testCloudFormationErrorResponse(cloudformation.ErrCodeTokenAlreadyExistsException, "A client request token already exists."), "text/xml"},
+		},
+		"ListStackSets": {
+			{200, testCloudFormationListStackSetsResponse("page-2", "tf-other", "tf-other:1d3a4b9c-57aa-4d6b-9d45-30f1c26f09a1", "ACTIVE"), "text/xml"},
+			{200, testCloudFormationListStackSetsResponse("", "tf-test", "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", "ACTIVE"), "text/xml"},
+		},
+		"DescribeStackSet": {
+			{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+		},
+		"ListStackInstances": {
+			{200, testCloudFormationListStackInstancesResponse(""), "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	if err := resourceAwsCloudFormationStackSetCreate(d, &AWSClient{cfconn: conn}); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if d.Id() != "tf-test" {
+		t.Fatalf("Expected ID %q, received: %q", "tf-test", d.Id())
+	}
+
+	creates := requests["CreateStackSet"]
+	if len(creates) != 2 {
+		t.Fatalf("Expected CreateStackSet to be retried once, received %d requests", len(creates))
+	}
+	token := creates[0].Get("ClientRequestToken")
+	if token == "" {
+		t.Fatalf("Expected a ClientRequestToken to be sent")
+	}
+	if retried := creates[1].Get("ClientRequestToken"); retried != token {
+		t.Fatalf("Expected the retry to reuse ClientRequestToken %q, received: %q", token, retried)
+	}
+
+	// The retried request returns no stack set ID, so it's looked up
+	if v := d.Get("stack_set_id").(string); v != "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346" {
+		t.Fatalf("Expected the stack set ID to be looked up, received: %q", v)
+	}
+	for _, r := range requests["ListStackSets"] {
+		if v := r.Get("Status"); v != "ACTIVE" {
+			t.Fatalf("Expected only active stack sets to be listed, received Status %q", v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetCreate_nameReused(t *testing.T) {
+	const newStackSetId = "tf-test:6b1e4a3c-8f2d-4c7b-a0e9-5d3f2c1b0a98"
+
+	// A stack set of the same name was just deleted and is still described by name
+	deleted := strings.Replace(testCloudFormationDescribeStackSetResponse, "<Status>ACTIVE</Status>", "<Status>DELETED</Status>", 1)
+	created := strings.Replace(testCloudFormationDescribeStackSetResponse, "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", newStackSetId, 1)
+
+	var mu sync.Mutex
+	var describedNames []string
+	closeFunc, sess, err := getMockedAwsApiSessionWithResponder("CloudFormation", func(r *http.Request, requestBody string) *awsMockResponse {
+		params, _ := url.ParseQuery(requestBody)
+		switch params.Get("Action") {
+		case "CreateStackSet":
+			return &awsMockResponse{200, testCloudFormationCreateStackSetResponse(newStackSetId), "text/xml"}
+		case "DescribeStackSet":
+			mu.Lock()
+			describedNames = append(describedNames, params.Get("StackSetName"))
+			mu.Unlock()
+			if params.Get("StackSetName") == newStackSetId {
+				return &awsMockResponse{200, created, "text/xml"}
+			}
+			return &awsMockResponse{200, deleted, "text/xml"}
+		case "ListStackInstances":
+			return &awsMockResponse{200, testCloudFormationListStackInstancesResponse(""), "text/xml"}
+		case "ListStackSetOperations":
+			return &awsMockResponse{200, testCloudFormationListStackSetOperationsResponse, "text/xml"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	if err := resourceAwsCloudFormationStackSetCreate(d, &AWSClient{cfconn: cloudformation.New(sess)}); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if d.Id() != "tf-test" {
+		t.Fatalf("Expected the created stack set to stay in state, received ID %q", d.Id())
+	}
+	if v := d.Get("stack_set_id").(string); v != newStackSetId {
+		t.Fatalf("Expected the created stack set ID %q, received: %q", newStackSetId, v)
+	}
+	if !reflect.DeepEqual(describedNames, []string{newStackSetId}) {
+		t.Fatalf("Expected the stack set to be described by its ID, received: %q", describedNames)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_tagsAndParameters(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Parameters":{"Name":{"Type":"String"}},"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"parameters":    map[string]interface{}{"Name": "old"},
+		"tags":          map[string]interface{}{"Environment": "dev"},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Parameters":{"Name":{"Type":"String"}},"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"parameters":    map[string]interface{}{"Name": "new"},
+		"tags":          map[string]interface{}{"Environment": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single UpdateStackSet request, received %d", len(updates))
+	}
+	update := updates[0]
+	if v := update.Get("UsePreviousTemplate"); v != "true" {
+		t.Fatalf("Expected UsePreviousTemplate to be sent, received: %q", v)
+	}
+	if _, ok := update["TemplateBody"]; ok {
+		t.Fatalf("Expected TemplateBody not to be sent, received: %q", update.Get("TemplateBody"))
+	}
+	if update.Get("Parameters.member.1.ParameterKey") != "Name" || update.Get("Parameters.member.1.ParameterValue") != "new" {
+		t.Fatalf("Expected the changed parameter to be sent, received: %v", update)
+	}
+	if update.Get("Tags.member.1.Key") != "Environment" || update.Get("Tags.member.1.Value") != "prod" {
+		t.Fatalf("Expected the changed tag to be sent, received: %v", update)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_capabilitiesCleared(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":  []interface{}{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single UpdateStackSet request, received %d", len(updates))
+	}
+	update := updates[0]
+	if v, ok := update["Capabilities"]; !ok || !reflect.DeepEqual(v, []string{""}) {
+		t.Fatalf("Expected an empty list of capabilities to be sent, received: %v", update)
+	}
+	if _, ok := update["Capabilities.member.1"]; ok {
+		t.Fatalf("Expected no capability to be sent, received: %q", update.Get("Capabilities.member.1"))
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_tagsCleared(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"tags": map[string]interface{}{
+			"Environment": "prod",
+		},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single UpdateStackSet request, received %d", len(updates))
+	}
+	update := updates[0]
+	if v, ok := update["Tags"]; !ok || !reflect.DeepEqual(v, []string{""}) {
+		t.Fatalf("Expected an empty list of tags to be sent, received: %v", update)
+	}
+	if _, ok := update["Tags.member.1.Key"]; ok {
+		t.Fatalf("Expected no tag to be sent, received: %q", update.Get("Tags.member.1.Key"))
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_descriptionCleared(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"description":   "Old description",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	if err == nil || !strings.Contains(err.Error(), "description can't be removed") {
+		t.Fatalf("Expected the description not to be removable, received: %v", err)
+	}
+	if len(requests["UpdateStackSet"]) != 0 {
+		t.Fatalf("Expected no UpdateStackSet request, received %d", len(requests["UpdateStackSet"]))
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_capabilitiesOnly(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":  []interface{}{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+	}, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":  []interface{}{"CAPABILITY_IAM"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	updates := requests["UpdateStackSet"]
+	if len(updates) != 1 {
+		t.Fatalf("Expected a single UpdateStackSet request, received %d", len(updates))
+	}
+	update := updates[0]
+	if v := update.Get("UsePreviousTemplate"); v != "true" {
+		t.Fatalf("Expected UsePreviousTemplate to be sent, received: %q", v)
+	}
+	if _, ok := update["TemplateBody"]; ok {
+		t.Fatalf("Expected TemplateBody not to be sent, received: %q", update.Get("TemplateBody"))
+	}
+	if v := update.Get("Capabilities.member.1"); v != "CAPABILITY_IAM" {
+		t.Fatalf("Expected CAPABILITY_IAM to be sent, received: %q", v)
+	}
+	if _, ok := update["Capabilities.member.2"]; ok {
+		t.Fatalf("Expected the removed capability not to be sent, received: %q", update.Get("Capabilities.member.2"))
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_descriptionOnly(t *testing.T) {
+	cases := []struct {
+		Old      string
+		New      string
+		Expected string
+	}{
+		{Old: "Old description", New: "New description", Expected: "New description"},
+		{Old: "", New: "Added description", Expected: "Added description"},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+			"name":          "tf-test",
+			"description":   tc.Old,
+			"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		}, map[string]interface{}{
+			"name":          "tf-test",
+			"description":   tc.New,
+			"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		updates := requests["UpdateStackSet"]
+		if len(updates) != 1 {
+			t.Fatalf("%d: Expected a single UpdateStackSet request, received %d", i, len(updates))
+		}
+		update := updates[0]
+		if v := update.Get("UsePreviousTemplate"); v != "true" {
+			t.Fatalf("%d: Expected UsePreviousTemplate to be sent, received: %q", i, v)
+		}
+		if _, ok := update["TemplateBody"]; ok {
+			t.Fatalf("%d: Expected TemplateBody not to be sent, received: %q", i, update.Get("TemplateBody"))
+		}
+		if v, ok := update["Description"]; !ok || v[0] != tc.Expected {
+			t.Fatalf("%d: Expected Description %q to be sent, received: %q", i, tc.Expected, v)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSet_numericParameters(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"parameters": map[string]interface{}{
+			"Port":  8080,
+			"Ratio": 0.5,
+		},
+	})
+
+	parameters := expandCloudFormationParameters(d.Get("parameters").(map[string]interface{}))
+	actual := make(map[string]string)
+	for _, p := range parameters {
+		actual[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+
+	// These are the values a DescribeStackSet returns for them
+	expected := map[string]string{"Port": "8080", "Ratio": "0.5"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected parameters %v, received %v", expected, actual)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_partialFailure(t *testing.T) {
+	responses := make(map[string][]*awsMockResponse)
+	for action, r := range testCloudFormationStackSetUpdateResponses {
+		responses[action] = r
+	}
+	responses["ListStackSetOperationResults"] = []*awsMockResponse{
+		{200, testCloudFormationListStackSetOperationResultsResponse("", "111111111111", "SUCCEEDED", "222222222222", "FAILED"), "text/xml"},
+	}
+
+	for _, treatAsError := range []bool{false, true} {
+		closeFunc, conn, _, err := getMockedCloudFormationConn(responses)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+			"name":                           "tf-test",
+			"description":                    "Old description",
+			"template_body":                  `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			"treat_partial_failure_as_error": treatAsError,
+		}, map[string]interface{}{
+			"name":                           "tf-test",
+			"description":                    "New description",
+			"template_body":                  `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			"treat_partial_failure_as_error": treatAsError,
+		})
+		closeFunc()
+
+		if !treatAsError {
+			if err != nil {
+				t.Fatalf("Expected a partial failure not to be an error, received: %s", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatal("Expected a partial failure to be an error")
+		}
+		if !strings.Contains(err.Error(), "account 222222222222 (us-east-1): Resource creation failed") {
+			t.Fatalf("Expected the error to name the failed account, received: %s", err)
+		}
+		if strings.Contains(err.Error(), "111111111111") {
+			t.Fatalf("Expected the error not to name the succeeded account, received: %s", err)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_stackIds(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("page-2",
+				"arn:aws:cloudformation:us-west-2:123456789012:stack/StackSet-tf-test-2/b", "",
+			), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("",
+				"arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-1/a",
+			), ContentType: "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationsResponse, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	d.SetId("tf-test")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	expected := []interface{}{
+		"arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-1/a",
+		"arn:aws:cloudformation:us-west-2:123456789012:stack/StackSet-tf-test-2/b",
+	}
+	if actual := d.Get("stack_ids").([]interface{}); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected stack_ids %q, received %q", expected, actual)
+	}
+
+	// The instance without a stack yet is OUTDATED, the others are CURRENT
+	if actual := d.Get("outdated_instance_count").(int); actual != 1 {
+		t.Fatalf("Expected outdated_instance_count 1, received %d", actual)
+	}
+
+	pages := requests["ListStackInstances"]
+	if len(pages) != 2 || pages[1].Get("NextToken") != "page-2" {
+		t.Fatalf("Expected both pages of stack instances to be listed, received: %v", pages)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetDelete_instances(t *testing.T) {
+	cases := []struct {
+		RetainStacks bool
+		Expected     string
+	}{
+		{RetainStacks: false, Expected: "false"},
+		{RetainStacks: true, Expected: "true"},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"ListStackInstances": {
+				{200, testCloudFormationListStackInstancesStatusResponse(
+					"123456789012", "us-east-1", "CURRENT",
+					"123456789012", "eu-west-1", "CURRENT",
+					"210987654321", "us-east-1", "CURRENT",
+				), "text/xml"},
+			},
+			"ListStackSetOperations": {
+				{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+			},
+			"DeleteStackInstances": {
+				{200, testCloudFormationDeleteStackInstancesResponse, "text/xml"},
+			},
+			"DescribeStackSetOperation": {
+				{200, testCloudFormationDescribeStackSetOperationResponse(cloudformation.StackSetOperationStatusSucceeded), "text/xml"},
+			},
+			"DeleteStackSet": {
+				{200, testCloudFormationDeleteStackSetResponse, "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := resourceAwsCloudFormationStackSet()
+		d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+			"name":                    "tf-test",
+			"template_body":           `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			"retain_stacks_on_delete": tc.RetainStacks,
+		})
+		d.SetId("tf-test")
+
+		_, err = r.Apply(d.State(), &terraform.InstanceDiff{Destroy: true}, &AWSClient{cfconn: conn})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		// Only the instances listed are targeted, the second account
+		// has none in eu-west-1
+		deletes := requests["DeleteStackInstances"]
+		if len(deletes) != 2 {
+			t.Fatalf("%d: Expected a DeleteStackInstances request per set of regions, received %d", i, len(deletes))
+		}
+		expected := []map[string]string{
+			{"Accounts.member.1": "123456789012", "Regions.member.1": "eu-west-1", "Regions.member.2": "us-east-1"},
+			{"Accounts.member.1": "210987654321", "Regions.member.1": "us-east-1"},
+		}
+		for j, input := range deletes {
+			for k, v := range expected[j] {
+				if actual := input.Get(k); actual != v {
+					t.Fatalf("%d: Expected DeleteStackInstances request %d %s to be %q, received: %v", i, j, k, v, input)
+				}
+			}
+			if input.Get("Accounts.member.2") != "" || input.Get(fmt.Sprintf("Regions.member.%d", len(expected[j]))) != "" {
+				t.Fatalf("%d: Expected DeleteStackInstances request %d to only target %v, received: %v", i, j, expected[j], input)
+			}
+			if v := input.Get("RetainStacks"); v != tc.Expected {
+				t.Fatalf("%d: Expected RetainStacks %q, received: %q", i, tc.Expected, v)
+			}
+		}
+		if n := len(requests["DeleteStackSet"]); n != 1 {
+			t.Fatalf("%d: Expected the stack set to be deleted, received %d DeleteStackSet requests", i, n)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetImport_stackSetId(t *testing.T) {
+	withParameters := strings.Replace(testCloudFormationDescribeStackSetCapabilitiesResponse, "</Capabilities>", `</Capabilities>
+      <Parameters>
+        <member>
+          <ParameterKey>VpcCidr</ParameterKey>
+          <ParameterValue>10.0.0.0/16</ParameterValue>
+        </member>
+      </Parameters>`, 1)
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: withParameters, ContentType: "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationsResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse(""), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackSet()
+	d := r.Data(nil)
+	d.SetId("tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+	meta := &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"}
+
+	results, err := r.Importer.State(d, meta)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected a single resource, received %d", len(results))
+	}
+	d = results[0]
+	if err := r.Read(d, meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if d.Id() != "tf-test" {
+		t.Fatalf("Expected the stack set ID to be resolved to the name, received ID %q", d.Id())
+	}
+	expected := map[string]interface{}{
+		"name":                    "tf-test",
+		"stack_set_id":            "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346",
+		"parameters.VpcCidr":      "10.0.0.0/16",
+		"capabilities.#":          2,
+		"prevent_update":          false,
+		"retain_stacks_on_delete": false,
+	}
+	for k, v := range expected {
+		if actual := d.Get(k); actual != v {
+			t.Fatalf("Expected %s to be %v, received: %v", k, v, actual)
+		}
+	}
+	if _, ok := d.State().Attributes["prevent_update"]; !ok {
+		t.Fatal("Expected prevent_update to be seeded with its default")
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetImport_deleted(t *testing.T) {
+	deleted := strings.Replace(testCloudFormationDescribeStackSetResponse, "<Status>ACTIVE</Status>", "<Status>DELETED</Status>", 1)
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: deleted, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackSet()
+	d := r.Data(nil)
+	d.SetId("tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+
+	_, err = r.Importer.State(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err == nil {
+		t.Fatal("Expected importing a deleted stack set to fail")
+	}
+	if !strings.Contains(err.Error(), "already deleted") {
+		t.Fatalf("Expected an error about the deleted stack set, received: %s", err)
+	}
+	if n := len(requests["DescribeStackSet"]); n != 1 {
+		t.Fatalf("Expected a single DescribeStackSet request, received %d", n)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetImport_nameReused(t *testing.T) {
+	const newStackSetId = "tf-test:6b1e4a3c-8f2d-4c7b-a0e9-5d3f2c1b0a98"
+
+	// A deleted stack set of the same name is still described by name
+	deleted := strings.Replace(testCloudFormationDescribeStackSetResponse, "<Status>ACTIVE</Status>", "<Status>DELETED</Status>", 1)
+	active := strings.Replace(testCloudFormationDescribeStackSetResponse, "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346", newStackSetId, 1)
+
+	var mu sync.Mutex
+	var describedNames []string
+	closeFunc, sess, err := getMockedAwsApiSessionWithResponder("CloudFormation", func(r *http.Request, requestBody string) *awsMockResponse {
+		params, _ := url.ParseQuery(requestBody)
+		switch params.Get("Action") {
+		case "DescribeStackSet":
+			mu.Lock()
+			describedNames = append(describedNames, params.Get("StackSetName"))
+			mu.Unlock()
+			if params.Get("StackSetName") == newStackSetId {
+				return &awsMockResponse{200, active, "text/xml"}
+			}
+			return &awsMockResponse{200, deleted, "text/xml"}
+		case "ListStackInstances":
+			return &awsMockResponse{200, testCloudFormationListStackInstancesResponse(""), "text/xml"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	r := resourceAwsCloudFormationStackSet()
+	d := r.Data(nil)
+	d.SetId(newStackSetId)
+	meta := &AWSClient{cfconn: cloudformation.New(sess), partition: "aws", region: "us-east-1", accountid: "123456789012"}
+
+	results, err := r.Importer.State(d, meta)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	d = results[0]
+	if v := d.Get("stack_set_id").(string); v != newStackSetId {
+		t.Fatalf("Expected the imported stack set ID %q, received: %q", newStackSetId, v)
+	}
+	if err := r.Read(d, meta); err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if d.Id() != "tf-test" {
+		t.Fatalf("Expected the imported stack set to stay in state, received ID %q", d.Id())
+	}
+	if !reflect.DeepEqual(describedNames, []string{newStackSetId, newStackSetId}) {
+		t.Fatalf("Expected the stack set to be described by its ID, received: %q", describedNames)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_deleted(t *testing.T) {
+	// A stack set was deleted and a new one created with the same name,
+	// but the deleted one is still described
+	deleted := strings.Replace(testCloudFormationDescribeStackSetResponse, "<Status>ACTIVE</Status>", "<Status>DELETED</Status>", 1)
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: deleted, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	d.SetId("tf-test")
+	d.Set("stack_set_id", "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+	if d.Id() != "" {
+		t.Fatalf("Expected the deleted stack set to be removed from state, received ID %q", d.Id())
+	}
+	if n := len(requests["ListStackInstances"]); n != 0 {
+		t.Fatalf("Expected the instances of the deleted stack set not to be listed, received %d requests", n)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_deployedRegions(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("page-2",
+				"arn:aws:cloudformation:us-west-2:123456789012:stack/StackSet-tf-test-1/a",
+				"arn:aws:cloudformation:eu-west-1:123456789012:stack/StackSet-tf-test-2/b",
+			), ContentType: "text/xml"},
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("",
+				"arn:aws:cloudformation:us-west-2:123456789012:stack/StackSet-tf-test-3/c", "",
+			), ContentType: "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationsResponse, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+	})
+	d.SetId("tf-test")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	// The instance without a stack yet is in us-east-1
+	expected := []string{"eu-west-1", "us-east-1", "us-west-2"}
+	regions := aws.StringValueSlice(expandStringList(d.Get("deployed_regions").(*schema.Set).List()))
+	sort.Strings(regions)
+	if !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("Expected deployed_regions %q, received %q", expected, regions)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_effectiveInstanceTags(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetResponse, ContentType: "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationsResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("",
+				"", "arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-1/a",
+			), ContentType: "text/xml"},
+		},
+		"DescribeStacks": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStacksTagsResponse, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"tags":          map[string]interface{}{"Environment": "test"},
+	})
+	d.SetId("tf-test")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	expected := map[string]interface{}{"Environment": "test"}
+	if actual := d.Get("effective_instance_tags").(map[string]interface{}); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Expected effective_instance_tags %v, received %v", expected, actual)
+	}
+
+	describes := requests["DescribeStacks"]
+	if len(describes) != 1 || describes[0].Get("StackName") != "arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-1/a" {
+		t.Fatalf("Expected the deployed stack to be described once, received: %v", describes)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_operationInProgress(t *testing.T) {
+	cases := []struct {
+		OperationsResponse string
+		ExpectedTemplate   string
+		ExpectedParameters map[string]interface{}
+	}{
+		{
+			OperationsResponse: testCloudFormationListStackSetOperationsRunningResponse,
+			ExpectedTemplate:   `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+			ExpectedParameters: map[string]interface{}{"QueueName": "tf-test"},
+		},
+		{
+			OperationsResponse: testCloudFormationListStackSetOperationsResponse,
+			ExpectedTemplate:   `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			ExpectedParameters: map[string]interface{}{},
+		},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"DescribeStackSet": {
+				{StatusCode: 200, Body: testCloudFormationDescribeStackSetResponse, ContentType: "text/xml"},
+			},
+			"ListStackInstances": {
+				{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("", ""), ContentType: "text/xml"},
+			},
+			"ListStackSetOperations": {
+				{StatusCode: 200, Body: tc.OperationsResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+			"parameters":    map[string]interface{}{"QueueName": "tf-test"},
+		})
+		d.SetId("tf-test")
+		d.Set("stack_set_id", "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+
+		err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+		closeFunc()
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		if actual := d.Get("template_body").(string); actual != tc.ExpectedTemplate {
+			t.Fatalf("%d: Expected template_body %q, received %q", i, tc.ExpectedTemplate, actual)
+		}
+		if actual := d.Get("parameters").(map[string]interface{}); !reflect.DeepEqual(actual, tc.ExpectedParameters) {
+			t.Fatalf("%d: Expected parameters %v, received %v", i, tc.ExpectedParameters, actual)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_currentInstances(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse("",
+				"arn:aws:cloudformation:eu-west-1:123456789012:stack/StackSet-tf-test/1",
+			), ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+	})
+	d.SetId("tf-test")
+	d.Set("stack_set_id", "tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	// No operation can be running with every instance current
+	if n := len(requests["ListStackSetOperations"]); n != 0 {
+		t.Fatalf("Expected no ListStackSetOperations requests, received %d", n)
+	}
+	if actual := d.Get("template_body").(string); actual != `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}` {
+		t.Fatalf("Expected the template_body to be refreshed, received %q", actual)
+	}
+}
+
+const testCloudFormationRequiredParameterTemplate = `{"Parameters":{"VpcCIDR":{"Type":"String"}},"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`
+
+func TestResourceAwsCloudFormationStackSetDiff_unknownParameters(t *testing.T) {
+	cases := []interface{}{
+		map[string]interface{}{
+			"VpcCIDR": "${var.unknown}",
+			"Name":    "known",
+		},
+		"${var.unknown}",
+	}
+
+	for i, parameters := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"GetTemplateSummary": {
+				{StatusCode: 200, Body: testCloudFormationGetTemplateSummaryRequiredParameterResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, diff, err := testCloudFormationStackSetDiff(t, conn, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": testCloudFormationRequiredParameterTemplate,
+		}, map[string]interface{}{
+			"name":                    "tf-test",
+			"template_body":           testCloudFormationRequiredParameterTemplate,
+			"parameters":              parameters,
+			"warn_missing_parameters": true,
+		})
+		closeFunc()
+
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+		if diff == nil || !diff.Attributes["parameters.%"].NewComputed {
+			t.Fatalf("%d: Expected the parameters to be computed, received: %#v", i, diff)
+		}
+		if n := len(requests["GetTemplateSummary"]); n != 0 {
+			t.Fatalf("%d: Expected no GetTemplateSummary requests, received %d", i, n)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetDiff_missingParameters(t *testing.T) {
+	cases := []struct {
+		WarnMissingParameters bool
+		ExpectedRequests      int
+	}{
+		{WarnMissingParameters: false, ExpectedRequests: 0},
+		{WarnMissingParameters: true, ExpectedRequests: 1},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"GetTemplateSummary": {
+				{StatusCode: 200, Body: testCloudFormationGetTemplateSummaryRequiredParameterResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A missing required parameter is only logged, the apply reports it
+		_, _, err = testCloudFormationStackSetDiff(t, conn, map[string]interface{}{
+			"name":          "tf-test",
+			"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		}, map[string]interface{}{
+			"name":                    "tf-test",
+			"template_body":           testCloudFormationRequiredParameterTemplate,
+			"warn_missing_parameters": tc.WarnMissingParameters,
+		})
+		closeFunc()
+
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+		if n := len(requests["GetTemplateSummary"]); n != tc.ExpectedRequests {
+			t.Fatalf("%d: Expected %d GetTemplateSummary requests, received %d", i, tc.ExpectedRequests, n)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetDiff_templateAttributes(t *testing.T) {
+	yamlTemplate := "Transform: AWS::Serverless-2016-10-31\nResources:\n  Topic:\n    Type: AWS::SNS::Topic\n"
+
+	cases := []struct {
+		Config   map[string]interface{}
+		Expected map[string]string
+	}{
+		{
+			Config: map[string]interface{}{
+				"name":          "tf-test",
+				"template_body": yamlTemplate,
+			},
+			Expected: map[string]string{
+				"template_body_hash":       cloudFormationTemplateHash(yamlTemplate),
+				"detected_template_format": "YAML",
+				"transforms.#":             "1",
+				"transforms.0":             "AWS::Serverless-2016-10-31",
+			},
+		},
+		// The template isn't known until apply
+		{
+			Config: map[string]interface{}{
+				"name":          "tf-test",
+				"template_body": "${var.unknown}",
+			},
+		},
+		{
+			Config: map[string]interface{}{
+				"name":         "tf-test",
+				"template_url": "https://s3.amazonaws.com/tf-test/template.yml",
+			},
+		},
+	}
+
+	// The state of the previous template, as refreshed by Read
+	jsonTemplate := `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`
+	old := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": jsonTemplate,
+	})
+	old.SetId("tf-test")
+	old.Set("template_body_hash", cloudFormationTemplateHash(jsonTemplate))
+	old.Set("detected_template_format", "JSON")
+	old.Set("transforms", []string{})
+
+	for i, tc := range cases {
+		_, diff, err := testCloudFormationStackSetDiffState(t, nil, old.State(), tc.Config)
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+
+		if tc.Expected == nil {
+			for _, k := range []string{"template_body_hash", "detected_template_format", "transforms.#"} {
+				if attr := diff.Attributes[k]; attr == nil || !attr.NewComputed {
+					t.Fatalf("%d: Expected %s to be computed, received: %#v", i, k, attr)
+				}
+			}
+			continue
+		}
+		for k, v := range tc.Expected {
+			if attr := diff.Attributes[k]; attr == nil || attr.NewComputed || attr.New != v {
+				t.Fatalf("%d: Expected %s to be planned as %q, received: %#v", i, k, v, attr)
+			}
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetDiff_checkRunningOperations(t *testing.T) {
+	cases := []struct {
+		CheckRunningOperations bool
+		ExpectedRequests       int
+	}{
+		{CheckRunningOperations: false, ExpectedRequests: 0},
+		{CheckRunningOperations: true, ExpectedRequests: 1},
+	}
+
+	for i, tc := range cases {
+		closeFunc, conn, requests, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+			"ListStackSetOperations": {
+				{StatusCode: 200, Body: testCloudFormationListStackSetOperationsRunningResponse, ContentType: "text/xml"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The running operation is only logged, the apply waits for it
+		_, _, err = testCloudFormationStackSetDiff(t, conn, map[string]interface{}{
+			"name":                     "tf-test",
+			"template_body":            `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			"check_running_operations": tc.CheckRunningOperations,
+		}, map[string]interface{}{
+			"name":                     "tf-test",
+			"template_body":            `{"Resources":{"Queue":{"Type":"AWS::SQS::Queue"}}}`,
+			"check_running_operations": tc.CheckRunningOperations,
+		})
+		closeFunc()
+
+		if err != nil {
+			t.Fatalf("%d: Expected no error, received: %s", i, err)
+		}
+		if n := len(requests["ListStackSetOperations"]); n != tc.ExpectedRequests {
+			t.Fatalf("%d: Expected %d ListStackSetOperations requests, received %d", i, tc.ExpectedRequests, n)
+		}
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetUpdate_terraformArgumentsOnly(t *testing.T) {
+	closeFunc, conn, requests, err := getMockedCloudFormationConn(testCloudFormationStackSetUpdateResponses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	err = testCloudFormationStackSetUpdate(t, conn, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":  []interface{}{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+	}, map[string]interface{}{
+		"name":                     "tf-test",
+		"template_body":            `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":             []interface{}{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"},
+		"check_running_operations": true,
+		"warn_missing_parameters":  true,
+		"deployment_window":        "22:00-04:00",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if n := len(requests["UpdateStackSet"]); n != 0 {
+		t.Fatalf("Expected no UpdateStackSet requests, received %d", n)
+	}
+}
+
+func TestResourceAwsCloudFormationStackSetRead_computed(t *testing.T) {
+	closeFunc, conn, _, err := getMockedCloudFormationConn(map[string][]*awsMockResponse{
+		"DescribeStackSet": {
+			{StatusCode: 200, Body: testCloudFormationDescribeStackSetCapabilitiesResponse, ContentType: "text/xml"},
+		},
+		"ListStackInstances": {
+			{StatusCode: 200, Body: testCloudFormationListStackInstancesResponse(""), ContentType: "text/xml"},
+		},
+		"ListStackSetOperations": {
+			{StatusCode: 200, Body: testCloudFormationListStackSetOperationsResponse, ContentType: "text/xml"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFunc()
+
+	d := schema.TestResourceDataRaw(t, resourceAwsCloudFormationStackSet().Schema, map[string]interface{}{
+		"name":          "tf-test",
+		"template_body": `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+		"capabilities":  []interface{}{"CAPABILITY_NAMED_IAM"},
+	})
+	d.SetId("tf-test")
+
+	err = resourceAwsCloudFormationStackSetRead(d, &AWSClient{cfconn: conn, partition: "aws", region: "us-east-1", accountid: "123456789012"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %s", err)
+	}
+
+	if v := d.Get("administration_role_name").(string); v != "AWSCloudFormationStackSetAdministrationRole" {
+		t.Fatalf("Expected the default administration role, received: %q", v)
+	}
+	if v := d.Get("execution_role_name").(string); v != "AWSCloudFormationStackSetExecutionRole" {
+		t.Fatalf("Expected the default execution role, received: %q", v)
+	}
+
+	capabilities := d.Get("capabilities").(*schema.Set)
+	if capabilities.Len() != 1 || !capabilities.Contains("CAPABILITY_NAMED_IAM") {
+		t.Fatalf("Expected only the configured capability, received: %v", capabilities.List())
+	}
+
+	effective := d.Get("effective_capabilities").(*schema.Set)
+	if effective.Len() != 2 || !effective.Contains("CAPABILITY_IAM") || !effective.Contains("CAPABILITY_NAMED_IAM") {
+		t.Fatalf("Expected the capability added by AWS to be effective, received: %v", effective.List())
+	}
+}
+
+func testAccCheckCloudFormationStackSetExists(n string, stackSet *cloudformation.StackSet) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		conn := testAccProvider.Meta().(*AWSClient).cfconn
+		resp, err := conn.DescribeStackSet(&cloudformation.DescribeStackSetInput{
+			StackSetName: aws.String(rs.Primary.ID),
+		})
+		if err != nil {
+			return err
+		}
+		if resp.StackSet == nil {
+			return fmt.Errorf("CloudFormation stack set not found")
+		}
+
+		*stackSet = *resp.StackSet
+
+		return nil
+	}
+}
+
+func testAccCheckCloudFormationStackSetParameter(stackSet *cloudformation.StackSet, key, value string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, p := range stackSet.Parameters {
+			if aws.StringValue(p.ParameterKey) != key {
+				continue
+			}
+			if actual := aws.StringValue(p.ParameterValue); actual != value {
+				return fmt.Errorf("Expected CloudFormation stack set parameter %q to be %q, got %q", key, value, actual)
+			}
+			return nil
+		}
+		return fmt.Errorf("CloudFormation stack set parameter %q not found", key)
+	}
+}
+
+func testAccCheckCloudFormationStackSetCapabilities(stackSet *cloudformation.StackSet, expected ...string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		actual := aws.StringValueSlice(stackSet.Capabilities)
+		sort.Strings(actual)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(actual, expected) {
+			return fmt.Errorf("Expected CloudFormation stack set capabilities %q, got %q", expected, actual)
+		}
+		return nil
+	}
+}
+
+func testAccCheckAWSCloudFormationStackSetDestroy(s *terraform.State) error {
+	conn := testAccProvider.Meta().(*AWSClient).cfconn
+
+	for _, rs := range s.RootModule().Resources {
+		if rs.Type != "aws_cloudformation_stack_set" {
+			continue
+		}
+
+		resp, err := conn.DescribeStackSet(&cloudformation.DescribeStackSetInput{
+			StackSetName: aws.String(rs.Primary.ID),
+		})
+		if err != nil {
+			if isAWSErr(err, cloudformation.ErrCodeStackSetNotFoundException, "") {
+				continue
+			}
+			return err
+		}
+
+		if aws.StringValue(resp.StackSet.Status) != cloudformation.StackSetStatusDeleted {
+			return fmt.Errorf("CloudFormation stack set still exists: %q", rs.Primary.ID)
+		}
+	}
+
+	return nil
+}
+
+func testAccAWSCloudFormationStackSetConfig(stackSetName, cidr string) string {
+	return fmt.Sprintf(`
+resource "aws_cloudformation_stack_set" "test" {
+  name        = "%[1]s"
+  description = "Terraform acceptance test"
+
+  parameters {
+    VpcCIDR = "%[2]s"
+  }
+
+  tags {
+    Name = "%[1]s"
+  }
+
+  template_body = <<TEMPLATE
+{
+  "Parameters" : {
+    "VpcCIDR" : {
+      "Type" : "String",
+      "Description" : "CIDR to be used for the VPC"
+    }
+  },
+  "Resources" : {
+    "MyVPC": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : { "Ref" : "VpcCIDR" },
+        "Tags" : [
+          {"Key": "Name", "Value": "Primary_CF_VPC"}
+        ]
+      }
+    }
+  }
+}
+TEMPLATE
+}
+`, stackSetName, cidr)
+}
+
+func testAccAWSCloudFormationStackSetConfig_preventUpdate(stackSetName, cidr string) string {
+	return fmt.Sprintf(`
+resource "aws_cloudformation_stack_set" "test" {
+  name           = "%[1]s"
+  prevent_update = true
+
+  parameters {
+    VpcCIDR = "%[2]s"
+  }
+
+  template_body = <<TEMPLATE
+{
+  "Parameters" : {
+    "VpcCIDR" : {
+      "Type" : "String",
+      "Description" : "CIDR to be used for the VPC"
+    }
+  },
+  "Resources" : {
+    "MyVPC": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : { "Ref" : "VpcCIDR" }
+      }
+    }
+  }
+}
+TEMPLATE
+}
+`, stackSetName, cidr)
+}
+
+// testCloudFormationStackSetUpdate plans and applies newConfig on top of
+// the state of a stack set created from oldConfig
+func testCloudFormationStackSetUpdate(t *testing.T, conn *cloudformation.CloudFormation, oldConfig, newConfig map[string]interface{}) error {
+	state, diff, err := testCloudFormationStackSetDiff(t, conn, oldConfig, newConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = resourceAwsCloudFormationStackSet().Apply(state, diff, &AWSClient{cfconn: conn})
+	return err
+}
+
+// testCloudFormationStackSetDiff plans changing a stack set created from the
+// old configuration to the new one, in which ${var.unknown} interpolates
+// to a value only known after apply
+func testCloudFormationStackSetDiff(t *testing.T, conn *cloudformation.CloudFormation, oldConfig, newConfig map[string]interface{}) (*terraform.InstanceState, *terraform.InstanceDiff, error) {
+	r := resourceAwsCloudFormationStackSet()
+
+	old := schema.TestResourceDataRaw(t, r.Schema, oldConfig)
+	old.SetId(old.Get("name").(string))
+
+	return testCloudFormationStackSetDiffState(t, conn, old.State(), newConfig)
+}
+
+// testCloudFormationStackSetDiffState plans the new config against the state
+func testCloudFormationStackSetDiffState(t *testing.T, conn *cloudformation.CloudFormation, state *terraform.InstanceState, newConfig map[string]interface{}) (*terraform.InstanceState, *terraform.InstanceDiff, error) {
+	r := resourceAwsCloudFormationStackSet()
+
+	rawConfig, err := config.NewRawConfig(newConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = rawConfig.Interpolate(map[string]ast.Variable{
+		"var.unknown": {Value: config.UnknownVariableValue, Type: ast.TypeUnknown},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	diff, err := r.Diff(state, terraform.NewResourceConfig(rawConfig), &AWSClient{cfconn: conn})
+	return state, diff, err
+}
+
+// getMockedCloudFormationConn establishes a mocked AWS API session answering
+// each CloudFormation API action with the given responses in order, the last one
+// being repeated. The parameters of all received requests are recorded by action.
+func getMockedCloudFormationConn(responses map[string][]*awsMockResponse) (func(), *cloudformation.CloudFormation, map[string][]url.Values, error) {
+	var mu sync.Mutex
+	requests := make(map[string][]url.Values)
+
+	closeFunc, sess, err := getMockedAwsApiSessionWithResponder("CloudFormation", func(r *http.Request, requestBody string) *awsMockResponse {
+		params, _ := url.ParseQuery(requestBody)
+		action := params.Get("Action")
+
+		mu.Lock()
+		requests[action] = append(requests[action], params)
+		n := len(requests[action])
+		mu.Unlock()
+
+		actionResponses, ok := responses[action]
+		if !ok || len(actionResponses) == 0 {
+			return nil
+		}
+		if n > len(actionResponses) {
+			n = len(actionResponses)
+		}
+		return actionResponses[n-1]
+	})
+
+	return closeFunc, cloudformation.New(sess), requests, err
+}
+
+func testCloudFormationErrorResponse(code, message string) string {
+	return fmt.Sprintf(`<ErrorResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>%s</Code>
+    <Message>%s</Message>
+  </Error>
+  <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+</ErrorResponse>`, code, message)
+}
+
+const testCloudFormationDescribeStackSetResponse = `<DescribeStackSetResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStackSetResult>
+    <StackSet>
+      <StackSetName>tf-test</StackSetName>
+      <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+      <Status>ACTIVE</Status>
+      <TemplateBody>{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}</TemplateBody>
+    </StackSet>
+  </DescribeStackSetResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DescribeStackSetResponse>`
+
+const testCloudFormationDescribeStackSetCapabilitiesResponse = `<DescribeStackSetResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStackSetResult>
+    <StackSet>
+      <StackSetName>tf-test</StackSetName>
+      <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+      <Status>ACTIVE</Status>
+      <TemplateBody>{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}</TemplateBody>
+      <Capabilities>
+        <member>CAPABILITY_IAM</member>
+        <member>CAPABILITY_NAMED_IAM</member>
+      </Capabilities>
+    </StackSet>
+  </DescribeStackSetResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DescribeStackSetResponse>`
+
+var testCloudFormationStackSetUpdateResponses = map[string][]*awsMockResponse{
+	"ListStackSetOperations": {
+		{200, testCloudFormationListStackSetOperationsResponse, "text/xml"},
+	},
+	"UpdateStackSet": {
+		{200, testCloudFormationUpdateStackSetResponse, "text/xml"},
+	},
+	"DescribeStackSetOperation": {
+		{200, testCloudFormationDescribeStackSetOperationResponse("SUCCEEDED"), "text/xml"},
+	},
+	"ListStackSetOperationResults": {
+		{200, testCloudFormationListStackSetOperationResultsResponse("", "111111111111", "SUCCEEDED"), "text/xml"},
+	},
+	"DescribeStackSet": {
+		{200, testCloudFormationDescribeStackSetResponse, "text/xml"},
+	},
+	"ListStackInstances": {
+		{200, testCloudFormationListStackInstancesResponse(""), "text/xml"},
+	},
+}
+
+const testCloudFormationListStackSetOperationsResponse = `<ListStackSetOperationsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackSetOperationsResult>
+    <Summaries/>
+  </ListStackSetOperationsResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackSetOperationsResponse>`
+
+const testCloudFormationListStackSetOperationsRunningResponse = `<ListStackSetOperationsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackSetOperationsResult>
+    <Summaries>
+      <member>
+        <OperationId>terraform-20171012000000000000000002</OperationId>
+        <Action>UPDATE</Action>
+        <Status>RUNNING</Status>
+      </member>
+    </Summaries>
+  </ListStackSetOperationsResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackSetOperationsResponse>`
+
+const testCloudFormationGetTemplateSummaryResponse = `<GetTemplateSummaryResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <GetTemplateSummaryResult>
+    <Parameters/>
+  </GetTemplateSummaryResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</GetTemplateSummaryResponse>`
+
+const testCloudFormationGetTemplateSummaryRequiredParameterResponse = `<GetTemplateSummaryResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <GetTemplateSummaryResult>
+    <Parameters>
+      <member>
+        <ParameterKey>VpcCIDR</ParameterKey>
+        <ParameterType>String</ParameterType>
+        <NoEcho>false</NoEcho>
+      </member>
+    </Parameters>
+  </GetTemplateSummaryResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</GetTemplateSummaryResponse>`
+
+func testCloudFormationCreateStackSetResponse(stackSetId string) string {
+	return fmt.Sprintf(`<CreateStackSetResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <CreateStackSetResult>
+    <StackSetId>%s</StackSetId>
+  </CreateStackSetResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</CreateStackSetResponse>`, stackSetId)
+}
+
+// testCloudFormationListStackSetsResponse returns a page of stack set
+// summaries, given as name, ID and status of each
+func testCloudFormationListStackSetsResponse(nextToken string, stackSets ...string) string {
+	var summaries bytes.Buffer
+	for i := 0; i < len(stackSets); i += 3 {
+		fmt.Fprintf(&summaries, `
+      <member>
+        <StackSetName>%s</StackSetName>
+        <StackSetId>%s</StackSetId>
+        <Status>%s</Status>
+      </member>`, stackSets[i], stackSets[i+1], stackSets[i+2])
+	}
+
+	var token string
+	if nextToken != "" {
+		token = fmt.Sprintf("\n    <NextToken>%s</NextToken>", nextToken)
+	}
+
+	return fmt.Sprintf(`<ListStackSetsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackSetsResult>
+    <Summaries>%s
+    </Summaries>%s
+  </ListStackSetsResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackSetsResponse>`, summaries.String(), token)
+}
+
+// testCloudFormationListStackInstancesResponse returns a page of stack
+// instances with the given stack IDs, an empty one meaning no stack yet.
+// Instances are in the region of their stack, otherwise in us-east-1.
+func testCloudFormationListStackInstancesResponse(nextToken string, stackIds ...string) string {
+	var summaries bytes.Buffer
+	for _, stackId := range stackIds {
+		region := "us-east-1"
+		if stackId != "" {
+			region = strings.Split(stackId, ":")[3]
+		}
+		fmt.Fprintf(&summaries, `
+      <member>
+        <StackSetId>tf-test:2df4b2b4-1fa9-4b44-8bf5-2bf71b0b7346</StackSetId>
+        <Account>123456789012</Account>
+        <Region>%s</Region>`, region)
+		if stackId != "" {
+			fmt.Fprintf(&summaries, `
+        <StackId>%s</StackId>
+        <Status>CURRENT</Status>`, stackId)
+		} else {
+			summaries.WriteString(`
+        <Status>OUTDATED</Status>`)
+		}
+		summaries.WriteString(`
+      </member>`)
+	}
+
+	var token string
+	if nextToken != "" {
+		token = fmt.Sprintf("\n    <NextToken>%s</NextToken>", nextToken)
+	}
+
+	return fmt.Sprintf(`<ListStackInstancesResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackInstancesResult>
+    <Summaries>%s
+    </Summaries>%s
+  </ListStackInstancesResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackInstancesResponse>`, summaries.String(), token)
+}
+
+// testCloudFormationListStackSetOperationsPageResponse returns a page of
+// operations for the given ID, status and creation timestamp triples
+func testCloudFormationListStackSetOperationsPageResponse(nextToken string, operations ...string) string {
+	var summaries bytes.Buffer
+	for i := 0; i < len(operations); i += 3 {
+		fmt.Fprintf(&summaries, `
+      <member>
+        <OperationId>%s</OperationId>
+        <Action>UPDATE</Action>
+        <Status>%s</Status>
+        <CreationTimestamp>%s</CreationTimestamp>
+      </member>`, operations[i], operations[i+1], operations[i+2])
+	}
+
+	var token string
+	if nextToken != "" {
+		token = fmt.Sprintf("\n    <NextToken>%s</NextToken>", nextToken)
+	}
+
+	return fmt.Sprintf(`<ListStackSetOperationsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackSetOperationsResult>
+    <Summaries>%s
+    </Summaries>%s
+  </ListStackSetOperationsResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackSetOperationsResponse>`, summaries.String(), token)
+}
+
+const testCloudFormationDescribeStacksTagsResponse = `<DescribeStacksResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStacksResult>
+    <Stacks>
+      <member>
+        <StackName>StackSet-tf-test-1</StackName>
+        <StackId>arn:aws:cloudformation:us-east-1:123456789012:stack/StackSet-tf-test-1/a</StackId>
+        <StackStatus>CREATE_COMPLETE</StackStatus>
+        <CreationTime>2017-10-12T10:00:00Z</CreationTime>
+        <Tags>
+          <member>
+            <Key>Environment</Key>
+            <Value>test</Value>
+          </member>
+          <member>
+            <Key>aws:cloudformation:stack-name</Key>
+            <Value>StackSet-tf-test-1</Value>
+          </member>
+        </Tags>
+      </member>
+    </Stacks>
+  </DescribeStacksResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DescribeStacksResponse>`
+
+const testCloudFormationUpdateStackSetResponse = `<UpdateStackSetResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <UpdateStackSetResult>
+    <OperationId>terraform-20171012000000000000000001</OperationId>
+  </UpdateStackSetResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</UpdateStackSetResponse>`
+
+const testCloudFormationStopStackSetOperationResponse = `<StopStackSetOperationResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <StopStackSetOperationResult/>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</StopStackSetOperationResponse>`
+
+const testCloudFormationDeleteStackSetResponse = `<DeleteStackSetResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DeleteStackSetResult/>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DeleteStackSetResponse>`
+
+func testCloudFormationDescribeStackSetOperationResponse(status string) string {
+	return fmt.Sprintf(`<DescribeStackSetOperationResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStackSetOperationResult>
+    <StackSetOperation>
+      <OperationId>terraform-20171012000000000000000001</OperationId>
+      <Action>UPDATE</Action>
+      <Status>%s</Status>
+    </StackSetOperation>
+  </DescribeStackSetOperationResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</DescribeStackSetOperationResponse>`, status)
+}
+
+// testCloudFormationListStackSetOperationResultsResponse returns a page of
+// results for the given account and status pairs, all in us-east-1
+func testCloudFormationListStackSetOperationResultsResponse(nextToken string, accountStatuses ...string) string {
+	var summaries bytes.Buffer
+	for i := 0; i < len(accountStatuses); i += 2 {
+		fmt.Fprintf(&summaries, `
+      <member>
+        <Account>%s</Account>
+        <Region>us-east-1</Region>
+        <Status>%s</Status>
+        <StatusReason>Resource creation failed</StatusReason>
+      </member>`, accountStatuses[i], accountStatuses[i+1])
+	}
+
+	var token string
+	if nextToken != "" {
+		token = fmt.Sprintf("\n    <NextToken>%s</NextToken>", nextToken)
+	}
+
+	return fmt.Sprintf(`<ListStackSetOperationResultsResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <ListStackSetOperationResultsResult>
+    <Summaries>%s
+    </Summaries>%s
+  </ListStackSetOperationResultsResult>
+  <ResponseMetadata>
+    <RequestId>1b206dd1-f9a8-11e5-becf-051c60f11c4a</RequestId>
+  </ResponseMetadata>
+</ListStackSetOperationResultsResponse>`, summaries.String(), token)
+}
+
+func testAccAWSCloudFormationStackSetConfig_capabilities(stackSetName, capabilities string) string {
+	return fmt.Sprintf(`
+resource "aws_cloudformation_stack_set" "test" {
+  name         = "%s"
+  capabilities = [%s]
+
+  template_body = <<TEMPLATE
+{
+  "Resources" : {
+    "Role": {
+      "Type" : "AWS::IAM::Role",
+      "Properties" : {
+        "AssumeRolePolicyDocument" : {
+          "Version": "2012-10-17",
+          "Statement": [
+            {
+              "Effect": "Allow",
+              "Principal": { "Service": "ec2.amazonaws.com" },
+              "Action": "sts:AssumeRole"
+            }
+          ]
+        }
+      }
+    }
+  }
+}
+TEMPLATE
+}
+`, stackSetName, capabilities)
+}
+
+func testAccAWSCloudFormationStackSetConfig_missingParameter(stackSetName string) string {
+	return fmt.Sprintf(`
+resource "aws_cloudformation_stack_set" "test" {
+  name                    = "%s"
+  warn_missing_parameters = true
+
+  template_body = <<TEMPLATE
+{
+  "Parameters" : {
+    "VpcCIDR" : {
+      "Type" : "String",
+      "Description" : "CIDR to be used for the VPC"
+    }
+  },
+  "Resources" : {
+    "MyVPC": {
+      "Type" : "AWS::EC2::VPC",
+      "Properties" : {
+        "CidrBlock" : { "Ref" : "VpcCIDR" }
+      }
+    }
+  }
+}
+TEMPLATE
+}
+`, stackSetName)
+}
@@ -1259,9 +1259,14 @@ func expandCloudFormationTags(tags map[string]interface{}) []*cloudformation.Tag
 	return cfTags
 }
 
+// flattenCloudFormationTags is flattening list of *cloudformation.Tag
+// while dropping AWS specific tags which cannot be managed by the user
 func flattenCloudFormationTags(cfTags []*cloudformation.Tag) map[string]string {
 	tags := make(map[string]string, len(cfTags))
 	for _, t := range cfTags {
+		if tagIgnoredGeneric(*t.Key) {
+			continue
+		}
 		tags[*t.Key] = *t.Value
 	}
 	return tags
@@ -1275,6 +1280,35 @@ func flattenCloudFormationOutputs(cfOutputs []*cloudformation.Output) map[string
 	return outputs
 }
 
+// expandCloudFormationStackSetOperationPreferences leaves out unset values,
+// as AWS rejects e.g. a failure tolerance count next to a percentage
+func expandCloudFormationStackSetOperationPreferences(configured []interface{}) *cloudformation.StackSetOperationPreferences {
+	if len(configured) == 0 || configured[0] == nil {
+		return nil
+	}
+
+	m := configured[0].(map[string]interface{})
+	preferences := &cloudformation.StackSetOperationPreferences{}
+
+	if v, ok := m["failure_tolerance_count"].(int); ok && v > 0 {
+		preferences.FailureToleranceCount = aws.Int64(int64(v))
+	}
+	if v, ok := m["failure_tolerance_percentage"].(int); ok && v > 0 {
+		preferences.FailureTolerancePercentage = aws.Int64(int64(v))
+	}
+	if v, ok := m["max_concurrent_count"].(int); ok && v > 0 {
+		preferences.MaxConcurrentCount = aws.Int64(int64(v))
+	}
+	if v, ok := m["max_concurrent_percentage"].(int); ok && v > 0 {
+		preferences.MaxConcurrentPercentage = aws.Int64(int64(v))
+	}
+	if v, ok := m["region_order"].([]interface{}); ok && len(v) > 0 {
+		preferences.RegionOrder = expandStringList(v)
+	}
+
+	return preferences
+}
+
 func flattenAsgSuspendedProcesses(list []*autoscaling.SuspendedProcess) []string {
 	strs := make([]string, 0, len(list))
 	for _, r := range list {
@@ -2016,6 +2050,50 @@ func normalizeCloudFormationTemplate(templateString interface{}) (string, error)
 	}
 }
 
+// cloudFormationTemplateFormat returns whether a template is written in
+// JSON or YAML
+func cloudFormationTemplateFormat(templateString string) string {
+	if looksLikeJsonString(templateString) {
+		return "JSON"
+	}
+	return "YAML"
+}
+
+// decodeCloudFormationTemplate decodes a JSON or YAML template into its
+// top-level sections
+func decodeCloudFormationTemplate(templateString string) (map[string]interface{}, error) {
+	template := make(map[string]interface{})
+	var err error
+	if looksLikeJsonString(templateString) {
+		err = json.Unmarshal([]byte(templateString), &template)
+	} else {
+		err = yaml.Unmarshal([]byte(templateString), &template)
+	}
+	return template, err
+}
+
+// flattenCloudFormationTemplateTransforms returns the macros declared by the
+// Transform section of a template, given either as a single name or a list
+func flattenCloudFormationTemplateTransforms(templateString string) ([]string, error) {
+	template, err := decodeCloudFormationTemplate(templateString)
+	if err != nil {
+		return nil, err
+	}
+
+	transforms := make([]string, 0)
+	switch t := template["Transform"].(type) {
+	case string:
+		transforms = append(transforms, t)
+	case []interface{}:
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				transforms = append(transforms, name)
+			}
+		}
+	}
+	return transforms, nil
+}
+
 func flattenInspectorTags(cfTags []*cloudformation.Tag) map[string]string {
 	tags := make(map[string]string, len(cfTags))
 	for _, t := range cfTags {
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/apigateway"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elasticache"
 	"github.com/aws/aws-sdk-go/service/elb"
@@ -905,6 +906,24 @@ func TestFlattenAsgEnabledMetrics(t *testing.T) {
 	}
 }
 
+func TestFlattenCloudFormationTags(t *testing.T) {
+	expanded := []*cloudformation.Tag{
+		&cloudformation.Tag{Key: aws.String("Name"), Value: aws.String("stack-set")},
+		&cloudformation.Tag{Key: aws.String("aws:cloudformation:stack-set-id"), Value: aws.String("stack-set:1234")},
+		&cloudformation.Tag{Key: aws.String("Environment"), Value: aws.String("test")},
+	}
+
+	result := flattenCloudFormationTags(expanded)
+
+	expected := map[string]string{
+		"Name":        "stack-set",
+		"Environment": "test",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
+
 func TestFlattenKinesisShardLevelMetrics(t *testing.T) {
 	expanded := []*kinesis.EnhancedMetrics{
 		&kinesis.EnhancedMetrics{
@@ -1323,6 +1342,153 @@ func TestNormalizeCloudFormationTemplate(t *testing.T) {
 	}
 }
 
+func TestNormalizeCloudFormationTemplate_yamlKeepsFormat(t *testing.T) {
+	yamlTemplate := `AWSTemplateFormatVersion: "2010-09-09"
+Parameters:
+  VpcCIDR:
+    Type: String
+Resources:
+  MyVPC:
+    Type: AWS::EC2::VPC
+    Properties:
+      CidrBlock: !Ref VpcCIDR
+`
+
+	actual, err := normalizeCloudFormationTemplate(yamlTemplate)
+	if err != nil {
+		t.Fatalf("Expected not to throw an error while parsing template, but got: %s", err)
+	}
+	if actual != yamlTemplate {
+		t.Fatalf("Expected the YAML template to be kept as written, got:\n\n%s", actual)
+	}
+
+	stateFunc := resourceAwsCloudFormationStackSet().Schema["template_body"].StateFunc
+	if actual := stateFunc(yamlTemplate); actual != yamlTemplate {
+		t.Fatalf("Expected the YAML template to be stored as written, got:\n\n%s", actual)
+	}
+}
+
+func TestCloudFormationTemplateFormat(t *testing.T) {
+	cases := []struct {
+		Template string
+		Expected string
+	}{
+		{
+			Template: `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			Expected: "JSON",
+		},
+		{
+			Template: `
+{
+  "Resources": {
+    "Topic": {"Type": "AWS::SNS::Topic"}
+  }
+}
+`,
+			Expected: "JSON",
+		},
+		{
+			Template: `Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+`,
+			Expected: "YAML",
+		},
+	}
+
+	for i, tc := range cases {
+		template, err := normalizeCloudFormationTemplate(tc.Template)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if actual := cloudFormationTemplateFormat(template); actual != tc.Expected {
+			t.Fatalf("%d: expected format %s, got %s", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestExpandCloudFormationStackSetOperationPreferences(t *testing.T) {
+	cases := []struct {
+		Configured []interface{}
+		Expected   *cloudformation.StackSetOperationPreferences
+	}{
+		{
+			Configured: []interface{}{},
+			Expected:   nil,
+		},
+		{
+			Configured: []interface{}{map[string]interface{}{
+				"failure_tolerance_count":      0,
+				"failure_tolerance_percentage": 10,
+				"max_concurrent_count":         3,
+				"max_concurrent_percentage":    0,
+				"region_order":                 []interface{}{"us-west-2", "us-east-1"},
+			}},
+			Expected: &cloudformation.StackSetOperationPreferences{
+				FailureTolerancePercentage: aws.Int64(10),
+				MaxConcurrentCount:         aws.Int64(3),
+				RegionOrder:                aws.StringSlice([]string{"us-west-2", "us-east-1"}),
+			},
+		},
+		{
+			Configured: []interface{}{map[string]interface{}{
+				"failure_tolerance_count":      0,
+				"failure_tolerance_percentage": 0,
+				"max_concurrent_count":         0,
+				"max_concurrent_percentage":    0,
+				"region_order":                 []interface{}{},
+			}},
+			Expected: &cloudformation.StackSetOperationPreferences{},
+		},
+	}
+
+	for i, tc := range cases {
+		actual := expandCloudFormationStackSetOperationPreferences(tc.Configured)
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: Expected %s, received: %s", i, tc.Expected, actual)
+		}
+	}
+}
+
+func TestFlattenCloudFormationTemplateTransforms(t *testing.T) {
+	cases := []struct {
+		Template string
+		Expected []string
+	}{
+		{
+			Template: `AWSTemplateFormatVersion: "2010-09-09"
+Transform: AWS::Serverless-2016-10-31
+Resources:
+  Function:
+    Type: AWS::Serverless::Function
+    Properties:
+      Handler: index.handler
+      Runtime: nodejs6.10
+      CodeUri: s3://tf-test/function.zip
+`,
+			Expected: []string{"AWS::Serverless-2016-10-31"},
+		},
+		{
+			Template: `{"Transform":["AWS::Serverless-2016-10-31","MyMacro"],"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			Expected: []string{"AWS::Serverless-2016-10-31", "MyMacro"},
+		},
+		{
+			Template: `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			Expected: []string{},
+		},
+	}
+
+	for i, tc := range cases {
+		actual, err := flattenCloudFormationTemplateTransforms(tc.Template)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("%d: expected transforms %v, got %v", i, tc.Expected, actual)
+		}
+	}
+}
+
 func TestCanonicalXML(t *testing.T) {
 	cases := []struct {
 		Name        string
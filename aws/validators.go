@@ -723,6 +723,59 @@ func validateCloudFormationTemplate(v interface{}, k string) (ws []string, error
 	return
 }
 
+// validateCloudFormationParameters warns about unquoted boolean parameter
+// values. Terraform stringifies map values before they reach the provider,
+// turning true and false into "1" and "0" rather than the "true" and "false"
+// CloudFormation expects, so only quoted booleans keep their meaning.
+// Numbers keep their HCL formatting, e.g. 8080 and 0.5.
+func validateCloudFormationParameters(v interface{}, k string) (ws []string, errors []error) {
+	for key, value := range v.(map[string]interface{}) {
+		if b, ok := value.(bool); ok {
+			stringified := "0"
+			if b {
+				stringified = "1"
+			}
+			ws = append(ws, fmt.Sprintf("%q: parameter %q is passed to CloudFormation as %q, quote it as \"%t\" to pass %t instead",
+				k, key, stringified, b, b))
+		}
+	}
+	return
+}
+
+// cloudFormationStackSetTemplateValidators check the structure of stack set
+// templates beyond their syntax. Additional rules can be registered by
+// appending to it.
+var cloudFormationStackSetTemplateValidators = []func(template map[string]interface{}) error{
+	validateCloudFormationTemplateResources,
+}
+
+func validateCloudFormationStackSetTemplate(v interface{}, k string) (ws []string, errors []error) {
+	ws, errors = validateCloudFormationTemplate(v, k)
+	if len(errors) > 0 || v.(string) == "" {
+		return
+	}
+
+	template, err := decodeCloudFormationTemplate(v.(string))
+	if err != nil {
+		errors = append(errors, fmt.Errorf("%q must contain a template object: %s", k, err))
+		return
+	}
+
+	for _, validate := range cloudFormationStackSetTemplateValidators {
+		if err := validate(template); err != nil {
+			errors = append(errors, fmt.Errorf("%q %s", k, err))
+		}
+	}
+	return
+}
+
+func validateCloudFormationTemplateResources(template map[string]interface{}) error {
+	if _, ok := template["Resources"]; !ok {
+		return fmt.Errorf("must declare the required top-level Resources section")
+	}
+	return nil
+}
+
 func validateApiGatewayIntegrationType(v interface{}, k string) (ws []string, errors []error) {
 	value := v.(string)
 
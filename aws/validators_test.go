@@ -1008,6 +1008,102 @@ func TestValidateCloudFormationTemplate(t *testing.T) {
 	}
 }
 
+func TestValidateCloudFormationParameters(t *testing.T) {
+	cases := []struct {
+		Value     map[string]interface{}
+		WarnCount int
+	}{
+		{
+			Value:     map[string]interface{}{"VpcCIDR": "10.0.0.0/16", "Enabled": "true"},
+			WarnCount: 0,
+		},
+		{
+			Value:     map[string]interface{}{"Port": 8080, "Ratio": 0.5},
+			WarnCount: 0,
+		},
+		{
+			Value:     map[string]interface{}{"Enabled": true},
+			WarnCount: 1,
+		},
+		{
+			Value:     map[string]interface{}{"Enabled": true, "Debug": false},
+			WarnCount: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		ws, errors := validateCloudFormationParameters(tc.Value, "parameters")
+		if len(errors) != 0 {
+			t.Fatalf("Expected %v not to trigger a validation error, got: %v", tc.Value, errors)
+		}
+		if len(ws) != tc.WarnCount {
+			t.Fatalf("Expected %d validation warnings for %v, got %d: %v", tc.WarnCount, tc.Value, len(ws), ws)
+		}
+	}
+}
+
+func TestValidateCloudFormationStackSetTemplate(t *testing.T) {
+	cases := []struct {
+		Value    string
+		ErrCount int
+	}{
+		{
+			Value:    `{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`,
+			ErrCount: 0,
+		},
+		{
+			Value:    "Resources:\n  Topic:\n    Type: AWS::SNS::Topic\n",
+			ErrCount: 0,
+		},
+		{
+			Value:    `{"Parameters":{"VpcCIDR":{"Type":"String"}}}`,
+			ErrCount: 1,
+		},
+		{
+			Value:    "Parameters:\n  VpcCIDR:\n    Type: String\n",
+			ErrCount: 1,
+		},
+		{
+			Value:    `["Resources"]`,
+			ErrCount: 1,
+		},
+		{
+			Value:    `{"abc":"`,
+			ErrCount: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		_, errors := validateCloudFormationStackSetTemplate(tc.Value, "template_body")
+		if len(errors) != tc.ErrCount {
+			t.Fatalf("Expected %d validation errors for %q, got %d: %v", tc.ErrCount, tc.Value, len(errors), errors)
+		}
+	}
+}
+
+func TestValidateCloudFormationStackSetTemplate_registeredValidator(t *testing.T) {
+	defer func(validators []func(map[string]interface{}) error) {
+		cloudFormationStackSetTemplateValidators = validators
+	}(cloudFormationStackSetTemplateValidators)
+
+	cloudFormationStackSetTemplateValidators = append(cloudFormationStackSetTemplateValidators, func(template map[string]interface{}) error {
+		if _, ok := template["Description"]; !ok {
+			return fmt.Errorf("must have a Description")
+		}
+		return nil
+	})
+
+	_, errors := validateCloudFormationStackSetTemplate(`{"Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`, "template_body")
+	if len(errors) != 1 {
+		t.Fatalf("Expected the registered validator to fail, got: %v", errors)
+	}
+
+	_, errors = validateCloudFormationStackSetTemplate(`{"Description":"test","Resources":{"Topic":{"Type":"AWS::SNS::Topic"}}}`, "template_body")
+	if len(errors) != 0 {
+		t.Fatalf("Expected no validation errors, got: %v", errors)
+	}
+}
+
 func TestValidateApiGatewayIntegrationType(t *testing.T) {
 	type testCases struct {
 		Value    string